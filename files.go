@@ -20,5 +20,5 @@ func GetHistoryFile() (string, error) {
 		return "", fmt.Errorf("could not determine user home directory: %w", err)
 	}
 
-	return path.Join(dir, ".wrash_history.yaml"), nil
+	return path.Join(dir, ".wrash_history.db"), nil
 }
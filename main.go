@@ -28,7 +28,7 @@ func run(ctx *cli.Context) error {
 
 	expanded, err := base.Expand(func(s string) string {
 		return env[s]
-	})
+	}, wrash.OsFS{}, nil)
 	if err != nil {
 		return fmt.Errorf("could not expaqnd args: %s", err)
 	}
@@ -42,23 +42,20 @@ func run(ctx *cli.Context) error {
 		return err
 	}
 
-	entries, err := loadHistoryEntries(historyPath)
+	history, err := wrash.NewSQLiteHistory(rawBase, historyPath)
 	if err != nil {
 		return err
 	}
 
-	historyWriter, err := os.Create(historyPath)
-	if err != nil {
-		return nil
-	}
-	defer historyWriter.Close()
-
-	history := wrash.NewHistory(rawBase, historyWriter, entries)
-
-	session, err := wrash.NewSession(rawBase,
+	opts := []wrash.Option{
 		wrash.OptionHistory(history),
 		wrash.OptionEnvironment(env),
-	)
+	}
+	if config := ctx.String("config"); config != "" {
+		opts = append(opts, wrash.OptionConfigPath(config))
+	}
+
+	session, err := wrash.NewSession(rawBase, opts...)
 	if err != nil {
 		return err
 	}
@@ -73,8 +70,13 @@ func main() {
 		Name:        "wrash",
 		Version:     Version,
 		Description: "turn wrap any command line utility into an interactive shell",
-		Flags:       []cli.Flag{},
-		Action:      run,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "config",
+				Usage: "path to a wrash config file declaring per-base completers and aliases (defaults to $XDG_CONFIG_HOME/wrash/config.yaml)",
+			},
+		},
+		Action: run,
 		Authors: []*cli.Author{
 			{
 				Name:  "Josh Meranda",
@@ -55,3 +55,19 @@ type ErrInvalidIdentifier struct {
 func (e ErrInvalidIdentifier) Error() string {
 	return fmt.Sprintf("invalid identifier: '%s'", e.Identifier)
 }
+
+// ErrExpansion wraps a failure that occurred while expanding a Node at
+// runtime, as opposed to a parse-time error - e.g. a CommandSubstitution
+// whose inner command failed to run. Callers can use errors.As to
+// distinguish the two.
+type ErrExpansion struct {
+	Cause error
+}
+
+func (e ErrExpansion) Error() string {
+	return fmt.Sprintf("expansion failed: %s", e.Cause)
+}
+
+func (e ErrExpansion) Unwrap() error {
+	return e.Cause
+}
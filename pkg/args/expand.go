@@ -0,0 +1,292 @@
+package args
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// expandWordValue runs the brace-expansion pass over value, then resolves
+// each resulting candidate as a literal or a glob (consulting fsys), same as
+// Word.Expand did before brace expansion existed. It's also used by
+// TildeExpansion, whose Suffix may itself contain braces and glob
+// characters (e.g. the "/pro*/{src,cmd}" in "~/pro*/{src,cmd}").
+func expandWordValue(fsys FS, value string) ([]string, error) {
+	var result []string
+
+	for _, candidate := range expandBraces(value) {
+		expanded, err := expandLiteralOrGlob(fsys, candidate)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, expanded...)
+	}
+
+	return result, nil
+}
+
+// expandLiteralOrGlob strips escaped wildcard characters from value, or -
+// if an unescaped wildcard remains - expands value as a glob against fsys.
+func expandLiteralOrGlob(fsys FS, value string) ([]string, error) {
+	if stripped, found := stripEscapedWildcards(value); !found {
+		return []string{stripped}, nil
+	}
+
+	paths, err := fsys.Glob(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand glob: %w", err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("word expanded to empty value")
+	}
+
+	return lo.Map(paths, func(path string, _ int) string {
+		if strings.Contains(path, " ") {
+			return "'" + path + "'"
+		}
+		return path
+	}), nil
+}
+
+// stripEscapedWildcards removes backslashes from escaped wildcards, and
+// returns false. If any wildcards are not escaped, returns empty string and
+// true.
+func stripEscapedWildcards(value string) (stripped string, foundUnescaped bool) {
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\':
+			i++
+			stripped += string(value[i])
+		case '*', '+', '?', '[':
+			return "", true
+		default:
+			stripped += string(c)
+		}
+	}
+
+	return
+}
+
+// expandBraces performs bash-style brace expansion on value: the first
+// top-level `{...}` group is expanded (recursively, so nested groups work),
+// taking the cartesian product of its items with whatever follows it in
+// value. A group that is neither a comma-list nor a range falls through as
+// literal text, and expansion continues with whatever follows it.
+func expandBraces(value string) []string {
+	start := strings.IndexByte(value, '{')
+	if start < 0 {
+		return []string{value}
+	}
+
+	end := matchingBrace(value, start)
+	if end < 0 {
+		return []string{value}
+	}
+
+	prefix := value[:start]
+	body := value[start+1 : end]
+	suffix := value[end+1:]
+
+	items, ok := braceItems(body)
+	if !ok {
+		return lo.Map(expandBraces(suffix), func(rest string, _ int) string {
+			return value[:end+1] + rest
+		})
+	}
+
+	var expandedItems []string
+	for _, item := range items {
+		expandedItems = append(expandedItems, expandBraces(item)...)
+	}
+
+	suffixes := expandBraces(suffix)
+
+	result := make([]string, 0, len(expandedItems)*len(suffixes))
+	for _, item := range expandedItems {
+		for _, sfx := range suffixes {
+			result = append(result, prefix+item+sfx)
+		}
+	}
+
+	return result
+}
+
+// matchingBrace returns the index of the `}` that closes the `{` at start,
+// accounting for brace nesting, or -1 if it's never closed.
+func matchingBrace(s string, start int) int {
+	depth := 0
+
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// braceItems interprets body, the contents of a `{...}` group, as either a
+// comma-separated list (`a,b,c`) or a numeric/alpha range (`1..5`, `a..e`,
+// `01..10..2`). ok is false if body is neither, meaning the group isn't a
+// real brace expansion and should be left as literal text.
+func braceItems(body string) (items []string, ok bool) {
+	if items, ok := rangeItems(body); ok {
+		return items, true
+	}
+
+	items = splitBraceItems(body)
+	if len(items) < 2 {
+		return nil, false
+	}
+
+	return items, true
+}
+
+// splitBraceItems splits body on every top-level comma, i.e. one that isn't
+// inside a nested `{...}` group.
+func splitBraceItems(body string) []string {
+	var items []string
+	var current strings.Builder
+
+	depth := 0
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '{':
+			depth++
+			current.WriteByte(c)
+		case c == '}':
+			depth--
+			current.WriteByte(c)
+		case c == ',' && depth == 0:
+			items = append(items, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+	items = append(items, current.String())
+
+	return items
+}
+
+// braceRange matches a `{N..M}` or `{N..M..STEP}` range, where N and M are
+// either both integers or both single letters.
+var braceRange = regexp.MustCompile(`^([A-Za-z]|-?\d+)\.\.([A-Za-z]|-?\d+)(?:\.\.(-?\d+))?$`)
+
+// rangeItems expands body as a `{N..M[..STEP]}` range, reporting ok=false
+// if body doesn't match that syntax, or mixes a numeric endpoint with an
+// alpha one.
+func rangeItems(body string) (items []string, ok bool) {
+	m := braceRange.FindStringSubmatch(body)
+	if m == nil {
+		return nil, false
+	}
+
+	start, end, step := m[1], m[2], m[3]
+
+	if isAlphaEndpoint(start) != isAlphaEndpoint(end) {
+		return nil, false
+	}
+
+	if isAlphaEndpoint(start) {
+		return alphaRange(start[0], end[0], step), true
+	}
+
+	return numericRange(start, end, step), true
+}
+
+func isAlphaEndpoint(s string) bool {
+	return len(s) == 1 && (s[0] < '0' || s[0] > '9')
+}
+
+// numericRange expands a `{N..M[..STEP]}` range, zero-padding the results
+// to match whichever endpoint (if either) was itself zero-padded, e.g.
+// `{01..03}` -> ["01", "02", "03"].
+func numericRange(startStr, endStr, stepStr string) []string {
+	start, _ := strconv.Atoi(startStr)
+	end, _ := strconv.Atoi(endStr)
+
+	step := 1
+	if stepStr != "" {
+		if parsed, err := strconv.Atoi(stepStr); err == nil {
+			step = parsed
+		}
+	}
+
+	width := 0
+	if isZeroPadded(startStr) || isZeroPadded(endStr) {
+		width = max(len(strings.TrimPrefix(startStr, "-")), len(strings.TrimPrefix(endStr, "-")))
+	}
+
+	return lo.Map(rangeSeq(start, end, step), func(n int, _ int) string {
+		return formatPadded(n, width)
+	})
+}
+
+// alphaRange expands a `{a..e[..STEP]}` range of single letters.
+func alphaRange(startC, endC byte, stepStr string) []string {
+	step := 1
+	if stepStr != "" {
+		if parsed, err := strconv.Atoi(stepStr); err == nil {
+			step = parsed
+		}
+	}
+
+	return lo.Map(rangeSeq(int(startC), int(endC), step), func(n int, _ int) string {
+		return string(rune(n))
+	})
+}
+
+// rangeSeq enumerates the integers from start to end inclusive, stepping by
+// step (its sign is inferred from start and end, not taken literally).
+func rangeSeq(start, end, step int) []int {
+	if step == 0 {
+		step = 1
+	}
+	if step < 0 {
+		step = -step
+	}
+	if start > end {
+		step = -step
+	}
+
+	var seq []int
+	for n := start; (step > 0 && n <= end) || (step < 0 && n >= end); n += step {
+		seq = append(seq, n)
+	}
+
+	return seq
+}
+
+func isZeroPadded(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	return len(s) > 1 && s[0] == '0'
+}
+
+func formatPadded(n, width int) string {
+	s := strconv.Itoa(n)
+
+	neg := strings.HasPrefix(s, "-")
+	if neg {
+		s = s[1:]
+	}
+	for len(s) < width {
+		s = "0" + s
+	}
+	if neg {
+		s = "-" + s
+	}
+
+	return s
+}
@@ -0,0 +1,93 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandBraces(t *testing.T) {
+	type testCase struct {
+		Name  string
+		Input string
+		Out   []string
+	}
+
+	testCases := []testCase{
+		{
+			Name:  "NoBraces",
+			Input: "abc",
+			Out:   []string{"abc"},
+		},
+		{
+			Name:  "CommaList",
+			Input: "a{b,c,d}e",
+			Out:   []string{"abe", "ace", "ade"},
+		},
+		{
+			Name:  "NumericRange",
+			Input: "file{1..3}",
+			Out:   []string{"file1", "file2", "file3"},
+		},
+		{
+			Name:  "NumericRangeDescending",
+			Input: "{3..1}",
+			Out:   []string{"3", "2", "1"},
+		},
+		{
+			Name:  "NumericRangeWithStep",
+			Input: "{0..10..5}",
+			Out:   []string{"0", "5", "10"},
+		},
+		{
+			Name:  "ZeroPaddedNumericRange",
+			Input: "{01..03}",
+			Out:   []string{"01", "02", "03"},
+		},
+		{
+			Name:  "AlphaRange",
+			Input: "{a..e..2}",
+			Out:   []string{"a", "c", "e"},
+		},
+		{
+			Name:  "NestedGroups",
+			Input: "{a,{b,c}}",
+			Out:   []string{"a", "b", "c"},
+		},
+		{
+			Name:  "MultipleGroups",
+			Input: "{a,b}{1,2}",
+			Out:   []string{"a1", "a2", "b1", "b2"},
+		},
+		{
+			Name:  "SingleItemFallsThroughAsLiteral",
+			Input: "{abc}",
+			Out:   []string{"{abc}"},
+		},
+		{
+			Name:  "UnterminatedBraceFallsThroughAsLiteral",
+			Input: "{a,b",
+			Out:   []string{"{a,b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			assert.Equal(t, tc.Out, expandBraces(tc.Input))
+		})
+	}
+}
+
+func TestTildeExpansion(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	actual, err := (&TildeExpansion{Suffix: "/projects"}).Expand(nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{home + "/projects"}, actual)
+}
+
+func TestTildeExpansionUnknownUser(t *testing.T) {
+	_, err := (&TildeExpansion{User: "no-such-user-xyz"}).Expand(nil, nil, nil)
+	assert.Error(t, err)
+}
@@ -1,6 +1,7 @@
 package args
 
 import (
+	"strings"
 	"unicode"
 )
 
@@ -49,6 +50,28 @@ LOOP:
 	}, i, nil
 }
 
+// nextTilde parses a leading `~` or `~user` prefix into a TildeExpansion,
+// consuming the rest of the word (up to the next space or quote) as its
+// Suffix, the same boundary nextWord itself stops at.
+func nextTilde(s string) (Node, int, error) {
+	word, end, err := nextWord(s)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	value := word.(*Word).Value
+
+	user, suffix := value[1:], ""
+	if i := strings.IndexByte(value[1:], '/'); i >= 0 {
+		user, suffix = value[1:1+i], value[1+i:]
+	}
+
+	return &TildeExpansion{
+		User:   user,
+		Suffix: suffix,
+	}, end, nil
+}
+
 func nextSingleQuoteTokens(s string) (Node, int, error) {
 	var contents string
 
@@ -110,6 +133,17 @@ func nextDoubleQuote(s string) (Node, int, error) {
 	for i := 1; i < len(s); i++ {
 		switch current := s[i]; current {
 		case '$':
+			if i+1 < len(s) && s[i+1] == '(' {
+				node, end, err := nextCommandSubstitution(s[i:])
+				if err != nil {
+					return nil, 0, err
+				}
+				node.(*CommandSubstitution).Quoted = true
+				nodes = append(nodes, node)
+				i += end
+				continue
+			}
+
 			i++
 			identifier, end, err := nextIdentifer(s[i:])
 			if err != nil {
@@ -119,6 +153,14 @@ func nextDoubleQuote(s string) (Node, int, error) {
 				Name: identifier,
 			})
 			i += end
+		case '`':
+			node, end, err := nextBacktickSubstitution(s[i:])
+			if err != nil {
+				return nil, 0, err
+			}
+			node.(*CommandSubstitution).Quoted = true
+			nodes = append(nodes, node)
+			i += end
 		case '"':
 			return &DoubleQuote{
 				Nodes: nodes,
@@ -141,6 +183,75 @@ func nextDoubleQuote(s string) (Node, int, error) {
 	}
 }
 
+// nextCommandSubstitution parses a `$(...)` command substitution starting
+// at s[0] == '$', s[1] == '(', tracking balanced parens (and any quoted
+// spans within them) so `$(echo $(date))` parses as a single substitution.
+func nextCommandSubstitution(s string) (Node, int, error) {
+	depth := 0
+	var quote byte
+
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; {
+		case quote != 0:
+			if c == '\\' && i+1 < len(s) {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return &CommandSubstitution{
+					Command: s[2:i],
+				}, i, nil
+			}
+		}
+	}
+
+	return nil, 0, ErrUnexpectedEOF{
+		Cause: ErrUnterminatedSequence{
+			Start: "$(",
+			End:   ")",
+		},
+	}
+}
+
+// nextBacktickSubstitution parses the legacy “ `...` “ form of a command
+// substitution starting at s[0] == '`'.
+func nextBacktickSubstitution(s string) (Node, int, error) {
+	var contents strings.Builder
+
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\':
+			i++
+			if i < len(s) {
+				if s[i] != '`' {
+					contents.WriteByte('\\')
+				}
+				contents.WriteByte(s[i])
+			}
+		case '`':
+			return &CommandSubstitution{
+				Command: contents.String(),
+			}, i, nil
+		default:
+			contents.WriteByte(c)
+		}
+	}
+
+	return nil, 0, ErrUnexpectedEOF{
+		Cause: ErrUnterminatedSequence{
+			Start: "`",
+			End:   "`",
+		},
+	}
+}
+
 func parse(s string) (Command, error) {
 	args := []Arg{}
 	var nodes []Node
@@ -157,6 +268,16 @@ func parse(s string) (Command, error) {
 
 		switch current := s[head]; current {
 		case '$':
+			if head+1 < len(s) && s[head+1] == '(' {
+				node, end, err := nextCommandSubstitution(s[head:])
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, node)
+				head += end
+				break
+			}
+
 			head++
 			identifier, end, err := nextIdentifer(s[head:])
 			if err != nil {
@@ -166,6 +287,13 @@ func parse(s string) (Command, error) {
 				Name: identifier,
 			})
 			head += end
+		case '`':
+			node, end, err := nextBacktickSubstitution(s[head:])
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			head += end
 		case '\'':
 			node, end, err := nextSingleQuoteTokens(s[head:])
 			if err != nil {
@@ -180,6 +308,25 @@ func parse(s string) (Command, error) {
 			}
 			nodes = append(nodes, node)
 			head += end
+		case '~':
+			if len(nodes) > 0 {
+				// a `~` mid-word (e.g. "foo~bar") is never a tilde
+				// expansion, only one starting a fresh word is.
+				node, end, err := nextWord(s[head:])
+				if err != nil {
+					return nil, err
+				}
+				nodes = append(nodes, node)
+				head += end
+				break
+			}
+
+			node, end, err := nextTilde(s[head:])
+			if err != nil {
+				return nil, err
+			}
+			nodes = append(nodes, node)
+			head += end
 		default:
 			node, end, err := nextWord(s[head:])
 			if err != nil {
@@ -205,3 +352,273 @@ func Parse(s string) (Command, error) {
 
 	return cmd, nil
 }
+
+// splitTopLevel splits s on every occurrence of sep that isn't inside a
+// single- or double-quoted span.
+func splitTopLevel(s string, sep byte) ([]string, error) {
+	var segments []string
+	var current strings.Builder
+
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(s) {
+				i++
+				current.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			current.WriteByte(c)
+		case c == sep:
+			segments = append(segments, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, ErrUnexpectedEOF{
+			Cause: ErrUnterminatedSequence{
+				Start: string(quote),
+				End:   string(quote),
+			},
+		}
+	}
+
+	segments = append(segments, current.String())
+
+	return segments, nil
+}
+
+// matchRedirectionOp reports whether s[i:] begins with a redirection
+// operator, and if so its kind and width. "2>" and "&>" only count as
+// operators at a word boundary, so a literal argument like "file2>out"
+// isn't misread as a stderr redirection.
+func matchRedirectionOp(s string, i int) (RedirectionKind, int, bool) {
+	atBoundary := i == 0 || unicode.IsSpace(rune(s[i-1]))
+
+	switch {
+	case strings.HasPrefix(s[i:], ">>"):
+		return RedirectAppend, 2, true
+	case atBoundary && strings.HasPrefix(s[i:], "&>"):
+		return RedirectOutAndErr, 2, true
+	case atBoundary && strings.HasPrefix(s[i:], "2>"):
+		return RedirectErr, 2, true
+	case strings.HasPrefix(s[i:], ">"):
+		return RedirectOut, 1, true
+	case strings.HasPrefix(s[i:], "<"):
+		return RedirectIn, 1, true
+	}
+
+	return "", 0, false
+}
+
+// nextRedirectionTarget skips leading whitespace in s and parses a single
+// word or quoted sequence as a redirection's target, returning the number
+// of characters of s consumed.
+func nextRedirectionTarget(s string) (Node, int, error) {
+	skip := 0
+	for skip < len(s) && unicode.IsSpace(rune(s[skip])) {
+		skip++
+	}
+
+	if skip >= len(s) {
+		return nil, 0, ErrUnexpectedEOF{
+			Cause: ErrUnexpectedToken{
+				Expected: []string{"redirection target"},
+			},
+		}
+	}
+
+	var node Node
+	var end int
+	var err error
+
+	switch s[skip] {
+	case '\'':
+		node, end, err = nextSingleQuoteTokens(s[skip:])
+	case '"':
+		node, end, err = nextDoubleQuote(s[skip:])
+	default:
+		node, end, err = nextWord(s[skip:])
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return node, skip + end + 1, nil
+}
+
+// parseStage extracts every redirection from a single pipeline stage,
+// parsing the remaining command text (with redirections and their targets
+// removed) as a normal Command.
+func parseStage(s string) (Stage, error) {
+	var redirections []Redirection
+	var cmdText strings.Builder
+
+	i := 0
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '\'':
+			_, end, err := nextSingleQuoteTokens(s[i:])
+			if err != nil {
+				return Stage{}, err
+			}
+			cmdText.WriteString(s[i : i+end+1])
+			i += end + 1
+		case c == '"':
+			_, end, err := nextDoubleQuote(s[i:])
+			if err != nil {
+				return Stage{}, err
+			}
+			cmdText.WriteString(s[i : i+end+1])
+			i += end + 1
+		default:
+			if kind, opLen, ok := matchRedirectionOp(s, i); ok {
+				target, end, err := nextRedirectionTarget(s[i+opLen:])
+				if err != nil {
+					return Stage{}, err
+				}
+
+				redirections = append(redirections, Redirection{
+					Kind:   kind,
+					Target: Arg{target},
+				})
+
+				i += opLen + end
+				continue
+			}
+
+			cmdText.WriteByte(c)
+			i++
+		}
+	}
+
+	cmd, err := parse(cmdText.String())
+	if err != nil {
+		return Stage{}, err
+	}
+
+	return Stage{
+		Command:      cmd,
+		Redirections: redirections,
+	}, nil
+}
+
+// ParsePipeline parses s as one or more `|`-separated Stages, each of which
+// may carry its own redirections (`<`, `>`, `>>`, `2>`, `&>`).
+func ParsePipeline(s string) (Pipeline, error) {
+	segments, err := splitTopLevel(s, '|')
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline := make(Pipeline, 0, len(segments))
+	for _, segment := range segments {
+		stage, err := parseStage(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		pipeline = append(pipeline, stage)
+	}
+
+	return pipeline, nil
+}
+
+// splitStatements splits s into the text of each top-level Statement along
+// with the StatementOp following it (empty for the final segment), honoring
+// quoted spans the same way splitTopLevel does. A lone `|` is left alone for
+// ParsePipeline to split later; only `||` is treated as a Statement boundary.
+func splitStatements(s string) ([]string, []StatementOp, error) {
+	var segments []string
+	var ops []StatementOp
+	var current strings.Builder
+
+	var quote byte
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && i+1 < len(s) {
+				i++
+				current.WriteByte(s[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			current.WriteByte(c)
+		case c == ';':
+			segments = append(segments, current.String())
+			ops = append(ops, OpSeq)
+			current.Reset()
+		case c == '&' && i+1 < len(s) && s[i+1] == '&':
+			segments = append(segments, current.String())
+			ops = append(ops, OpAnd)
+			current.Reset()
+			i++
+		case c == '|' && i+1 < len(s) && s[i+1] == '|':
+			segments = append(segments, current.String())
+			ops = append(ops, OpOr)
+			current.Reset()
+			i++
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	if quote != 0 {
+		return nil, nil, ErrUnexpectedEOF{
+			Cause: ErrUnterminatedSequence{
+				Start: string(quote),
+				End:   string(quote),
+			},
+		}
+	}
+
+	segments = append(segments, current.String())
+
+	return segments, ops, nil
+}
+
+// ParseScript parses s as a sequence of Statements separated by `;`, `&&`,
+// or `||`, each of which is itself parsed by ParsePipeline.
+func ParseScript(s string) (Script, error) {
+	segments, ops, err := splitStatements(s)
+	if err != nil {
+		return nil, err
+	}
+
+	script := make(Script, 0, len(segments))
+	for i, segment := range segments {
+		pipeline, err := ParsePipeline(segment)
+		if err != nil {
+			return nil, err
+		}
+
+		var op StatementOp
+		if i < len(ops) {
+			op = ops[i]
+		}
+
+		script = append(script, Statement{
+			Pipeline: pipeline,
+			Op:       op,
+		})
+	}
+
+	return script, nil
+}
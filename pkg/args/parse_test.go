@@ -74,6 +74,45 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:  "Tilde",
+			Input: "~/projects/*",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&TildeExpansion{
+							Suffix: "/projects/*",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "TildeWithUser",
+			Input: "~someuser",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&TildeExpansion{
+							User: "someuser",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "TildeMidWordIsNotExpansion",
+			Input: "foo~bar",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&Word{
+							Value: "foo~bar",
+						},
+					},
+				},
+			},
+		},
 		{
 			Name:  "UnterminatedSingleQuote",
 			Input: "'abc",
@@ -98,6 +137,79 @@ func TestParse(t *testing.T) {
 				},
 			},
 		},
+		{
+			Name:  "CommandSubstitution",
+			Input: "$(git branch --show-current)",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&CommandSubstitution{
+							Command: "git branch --show-current",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "NestedCommandSubstitution",
+			Input: "$(echo $(date))",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&CommandSubstitution{
+							Command: "echo $(date)",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "BacktickCommandSubstitution",
+			Input: "`git branch --show-current`",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&CommandSubstitution{
+							Command: "git branch --show-current",
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "CommandSubstitutionInDoubleQuote",
+			Input: "\"$(git branch --show-current) checked out\"",
+			Result: result{
+				Cmd: Command{
+					Arg{
+						&DoubleQuote{
+							Nodes: []Node{
+								&CommandSubstitution{
+									Command: "git branch --show-current",
+									Quoted:  true,
+								},
+								&Word{
+									Value:    " checked out",
+									IsQuoted: true,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "UnterminatedCommandSubstitution",
+			Input: "$(echo",
+			Result: result{
+				Err: ErrUnexpectedEOF{
+					Cause: ErrUnterminatedSequence{
+						Start: "$(",
+						End:   ")",
+					},
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,203 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/r3labs/diff/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePipeline(t *testing.T) {
+	type result struct {
+		Pipeline Pipeline
+		Err      error
+	}
+
+	type testCase struct {
+		Name   string
+		Input  string
+		Result result
+	}
+
+	testCases := []testCase{
+		{
+			Name:  "SingleStageNoRedirection",
+			Input: "history",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "history"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "TwoStagePipe",
+			Input: "history | grep foo",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "history"}},
+						},
+					},
+					{
+						Command: Command{
+							Arg{&Word{Value: "grep"}},
+							Arg{&Word{Value: "foo"}},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "OutputRedirection",
+			Input: "history > out.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "history"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectOut,
+								Target: Arg{&Word{Value: "out.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "AppendRedirectionNoSpace",
+			Input: "history>>out.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "history"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectAppend,
+								Target: Arg{&Word{Value: "out.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "StderrRedirection",
+			Input: "cmd 2> err.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "cmd"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectErr,
+								Target: Arg{&Word{Value: "err.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "CombinedRedirection",
+			Input: "cmd &> all.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "cmd"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectOutAndErr,
+								Target: Arg{&Word{Value: "all.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "InputRedirectionDoesNotConfuseArgStartingWithTwo",
+			Input: "cmd 2ndarg < in.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "cmd"}},
+							Arg{&Word{Value: "2ndarg"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectIn,
+								Target: Arg{&Word{Value: "in.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "PipeAndRedirectionTogether",
+			Input: "history | grep foo > out.txt",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "history"}},
+						},
+					},
+					{
+						Command: Command{
+							Arg{&Word{Value: "grep"}},
+							Arg{&Word{Value: "foo"}},
+						},
+						Redirections: []Redirection{
+							{
+								Kind:   RedirectOut,
+								Target: Arg{&Word{Value: "out.txt"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "PipeInsideQuotesIsNotAStageBoundary",
+			Input: "echo 'a | b'",
+			Result: result{
+				Pipeline: Pipeline{
+					{
+						Command: Command{
+							Arg{&Word{Value: "echo"}},
+							Arg{&SingleQuote{Value: "a | b"}},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			pipeline, err := ParsePipeline(tc.Input)
+			assert.Equal(t, tc.Result.Err, err)
+
+			changelog, err := diff.Diff(tc.Result.Pipeline, pipeline)
+			require.NoError(t, err)
+			assert.Empty(t, changelog)
+		})
+	}
+}
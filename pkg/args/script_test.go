@@ -0,0 +1,192 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/r3labs/diff/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScript(t *testing.T) {
+	type result struct {
+		Script Script
+		Err    error
+	}
+
+	type testCase struct {
+		Name   string
+		Input  string
+		Result result
+	}
+
+	testCases := []testCase{
+		{
+			Name:  "SingleStatement",
+			Input: "history",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "history"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "Sequence",
+			Input: "echo a; echo b",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "echo"}},
+									Arg{&Word{Value: "a"}},
+								},
+							},
+						},
+						Op: OpSeq,
+					},
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "echo"}},
+									Arg{&Word{Value: "b"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "And",
+			Input: "make build && make test",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "make"}},
+									Arg{&Word{Value: "build"}},
+								},
+							},
+						},
+						Op: OpAnd,
+					},
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "make"}},
+									Arg{&Word{Value: "test"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "Or",
+			Input: "make test || echo failed",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "make"}},
+									Arg{&Word{Value: "test"}},
+								},
+							},
+						},
+						Op: OpOr,
+					},
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "echo"}},
+									Arg{&Word{Value: "failed"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "OrDoesNotConfusePipeBoundary",
+			Input: "history | grep foo || echo none",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "history"}},
+								},
+							},
+							{
+								Command: Command{
+									Arg{&Word{Value: "grep"}},
+									Arg{&Word{Value: "foo"}},
+								},
+							},
+						},
+						Op: OpOr,
+					},
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "echo"}},
+									Arg{&Word{Value: "none"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:  "OperatorInsideQuotesIsNotAStatementBoundary",
+			Input: "echo 'a && b'",
+			Result: result{
+				Script: Script{
+					{
+						Pipeline: Pipeline{
+							{
+								Command: Command{
+									Arg{&Word{Value: "echo"}},
+									Arg{&SingleQuote{Value: "a && b"}},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			script, err := ParseScript(tc.Input)
+			assert.Equal(t, tc.Result.Err, err)
+
+			changelog, err := diff.Diff(tc.Result.Script, script)
+			require.NoError(t, err)
+			assert.Empty(t, changelog)
+		})
+	}
+}
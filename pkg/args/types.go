@@ -2,7 +2,8 @@ package args
 
 import (
 	"fmt"
-	"path/filepath"
+	"os"
+	"os/user"
 	"strings"
 
 	"github.com/samber/lo"
@@ -10,6 +11,22 @@ import (
 
 type environment func(string) string
 
+// FS is the filesystem glob expansion consults to resolve a wildcard word
+// into the paths it matches. Callers pass their own FS (e.g. wrash.FS) in
+// place of the real filesystem to sandbox expansion or make it testable;
+// any type exposing a matching Glob method satisfies this interface.
+type FS interface {
+	Glob(pattern string) ([]string, error)
+}
+
+// Executor runs a CommandSubstitution's inner command and returns its
+// captured stdout. Callers pass their own (e.g. a wrash.Session), wired
+// through the same channel as FS, so args has no need to import its parent
+// package.
+type Executor interface {
+	Execute(command string) (string, error)
+}
+
 type Position struct {
 	Line int
 	Col  int
@@ -17,7 +34,7 @@ type Position struct {
 
 type Node interface {
 	// Returns the value of the node after expansion. If the node shuold be split accross multiple arguments (as for glob expansions), it will return multiple values.
-	Expand(environment) ([]string, error)
+	Expand(environment, FS, Executor) ([]string, error)
 	Arg() string
 }
 
@@ -26,60 +43,63 @@ type Word struct {
 	IsQuoted bool
 }
 
-// stripEscappedWildcards removes backslashes from escaped wildards, and rerturns false. If any wildcards are not escape returns empty string.
-func (w *Word) stripEscappedWildcards() (stripped string, foundUnescapped bool) {
-	for i := 0; i < len(w.Value); i++ {
-		switch c := w.Value[i]; c {
-		case '\\':
-			i++
-			stripped += string(w.Value[i])
-		case '*', '+', '?', '[':
-			return "", true
-		default:
-			stripped += string(c)
-		}
+func (w *Word) Expand(_ environment, fsys FS, _ Executor) ([]string, error) {
+	if w.IsQuoted {
+		return []string{w.Value}, nil
 	}
 
-	return
+	return expandWordValue(fsys, w.Value)
 }
 
-func (w *Word) Expand(environment) ([]string, error) {
-	if w.IsQuoted {
-		return []string{w.Value}, nil
-	}
+func (w *Word) Arg() string {
+	return w.Value
+}
 
-	if stripped, found := w.stripEscappedWildcards(); !found {
-		return []string{stripped}, nil
-	}
+// TildeExpansion resolves a `~` or `~user` prefix found at the start of an
+// unquoted word. Suffix holds the rest of the word (e.g. the "/projects" in
+// "~/projects"), which - like the rest of Word.Expand - still goes through
+// brace and glob expansion.
+type TildeExpansion struct {
+	User   string
+	Suffix string
+}
 
-	paths, err := filepath.Glob(w.Value)
+func (t *TildeExpansion) Expand(_ environment, fsys FS, _ Executor) ([]string, error) {
+	home, err := t.homeDir()
 	if err != nil {
-		return nil, fmt.Errorf("failed to expand glob: %w", err)
+		return nil, err
 	}
 
-	if len(paths) == 0 {
-		return nil, fmt.Errorf("word expanded to empty value")
-	}
+	return expandWordValue(fsys, home+t.Suffix)
+}
 
-	// todo: do something when there are no matches
-	return lo.Map(paths, func(path string, _ int) string {
-		if strings.Contains(path, " ") {
-			return "'" + path + "'"
-		} else {
-			return path
+func (t *TildeExpansion) homeDir() (string, error) {
+	if t.User == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("could not determine home directory: %w", err)
 		}
-	}), nil
+
+		return home, nil
+	}
+
+	u, err := user.Lookup(t.User)
+	if err != nil {
+		return "", fmt.Errorf("could not look up user %q: %w", t.User, err)
+	}
+
+	return u.HomeDir, nil
 }
 
-func (w *Word) Arg() string {
-	return w.Value
+func (t *TildeExpansion) Arg() string {
+	return "~" + t.User + t.Suffix
 }
 
 type SingleQuote struct {
 	Value string
 }
 
-func (q *SingleQuote) Expand(environment) ([]string, error) {
+func (q *SingleQuote) Expand(environment, FS, Executor) ([]string, error) {
 	return []string{q.Value}, nil
 }
 
@@ -91,10 +111,10 @@ type DoubleQuote struct {
 	Nodes []Node
 }
 
-func (q *DoubleQuote) Expand(env environment) ([]string, error) {
+func (q *DoubleQuote) Expand(env environment, fsys FS, exec Executor) ([]string, error) {
 	var acc string
 	for _, node := range q.Nodes {
-		expanded, err := node.Expand(env)
+		expanded, err := node.Expand(env, fsys, exec)
 		if err != nil {
 			return nil, err
 		}
@@ -114,7 +134,7 @@ type VariableExpansion struct {
 	Name string
 }
 
-func (q *VariableExpansion) Expand(env environment) ([]string, error) {
+func (q *VariableExpansion) Expand(env environment, _ FS, _ Executor) ([]string, error) {
 	return []string{env(q.Name)}, nil
 }
 
@@ -122,14 +142,52 @@ func (q *VariableExpansion) Arg() string {
 	return "$" + q.Name
 }
 
+// CommandSubstitution is the `$(...)` or legacy backtick form of a word:
+// Command is the raw, unparsed text between the delimiters, which is itself
+// parsed and run at expansion time, with its captured stdout spliced into
+// the surrounding Arg. Quoted reports whether the substitution appeared
+// inside a DoubleQuote, in which case its output is kept as a single token
+// instead of being word-split.
+type CommandSubstitution struct {
+	Command string
+	Quoted  bool
+}
+
+func (c *CommandSubstitution) Expand(_ environment, _ FS, exec Executor) ([]string, error) {
+	if exec == nil {
+		return nil, ErrExpansion{Cause: fmt.Errorf("command substitution is not supported here")}
+	}
+
+	out, err := exec.Execute(c.Command)
+	if err != nil {
+		return nil, ErrExpansion{Cause: fmt.Errorf("could not run command substitution %q: %w", c.Command, err)}
+	}
+
+	out = strings.TrimRight(out, "\n")
+
+	if c.Quoted {
+		return []string{out}, nil
+	}
+
+	if fields := strings.Fields(out); len(fields) > 0 {
+		return fields, nil
+	}
+
+	return []string{""}, nil
+}
+
+func (c *CommandSubstitution) Arg() string {
+	return "$(" + c.Command + ")"
+}
+
 type Arg []Node
 
-func (arg Arg) Expand(env environment) ([]string, error) {
+func (arg Arg) Expand(env environment, fsys FS, exec Executor) ([]string, error) {
 	var err error
 
 	result := lo.FlatMap(arg, func(node Node, _ int) []string {
 		var expanded []string
-		expanded, err = node.Expand(env)
+		expanded, err = node.Expand(env, fsys, exec)
 		return expanded
 	})
 
@@ -142,12 +200,12 @@ func (arg Arg) Expand(env environment) ([]string, error) {
 
 type Command []Arg
 
-func (cmd Command) Expand(env environment) ([]string, error) {
+func (cmd Command) Expand(env environment, fsys FS, exec Executor) ([]string, error) {
 	var err error
 
 	result := lo.FlatMap(cmd, func(arg Arg, _ int) []string {
 		var expanded []string
-		expanded, err = arg.Expand(env)
+		expanded, err = arg.Expand(env, fsys, exec)
 		return expanded
 	})
 
@@ -165,3 +223,79 @@ func (cmd Command) Args() []string {
 		}, "")
 	})
 }
+
+// RedirectionKind identifies which stream a Redirection targets, and
+// whether its target file is truncated or appended.
+type RedirectionKind string
+
+const (
+	RedirectIn        RedirectionKind = "<"
+	RedirectOut       RedirectionKind = ">"
+	RedirectAppend    RedirectionKind = ">>"
+	RedirectErr       RedirectionKind = "2>"
+	RedirectOutAndErr RedirectionKind = "&>"
+)
+
+// Redirection attaches a stream target to a Stage, e.g. the `> out.txt` in
+// `history > out.txt`.
+type Redirection struct {
+	Kind   RedirectionKind
+	Target Arg
+}
+
+// ExpandTarget resolves the Redirection's file path, returning an error if
+// it doesn't expand to exactly one value (e.g. an unescaped glob matching
+// more than one file).
+func (r Redirection) ExpandTarget(env environment, fsys FS, exec Executor) (string, error) {
+	expanded, err := r.Target.Expand(env, fsys, exec)
+	if err != nil {
+		return "", err
+	}
+
+	if len(expanded) != 1 {
+		return "", fmt.Errorf("redirection target must expand to exactly one value, got %d", len(expanded))
+	}
+
+	return expanded[0], nil
+}
+
+// Stage is a single command within a Pipeline, along with any redirections
+// attached directly to it.
+type Stage struct {
+	Command      Command
+	Redirections []Redirection
+}
+
+func (s Stage) Expand(env environment, fsys FS, exec Executor) ([]string, error) {
+	return s.Command.Expand(env, fsys, exec)
+}
+
+// Pipeline is one or more Stages connected by `|`, e.g.
+// `history | grep foo > out.txt`.
+type Pipeline []Stage
+
+// StatementOp is the operator joining a Statement to the one that follows
+// it in a Script, controlling whether that next Statement runs based on
+// this Statement's exit code.
+type StatementOp string
+
+const (
+	// OpSeq always runs the next Statement, regardless of exit code (`;`).
+	OpSeq StatementOp = ";"
+	// OpAnd only runs the next Statement if this one exits zero (`&&`).
+	OpAnd StatementOp = "&&"
+	// OpOr only runs the next Statement if this one exits non-zero (`||`).
+	OpOr StatementOp = "||"
+)
+
+// Statement is a single Pipeline within a Script, along with the operator
+// joining it to the following Statement. Op is empty for the last Statement
+// in a Script.
+type Statement struct {
+	Pipeline Pipeline
+	Op       StatementOp
+}
+
+// Script is a sequence of Statements separated by `;`, `&&`, or `||`, e.g.
+// `make build && make test || echo failed; echo done`.
+type Script []Statement
@@ -1,14 +1,16 @@
 package args
 
 import (
+	"fmt"
 	"path"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-var testDir = path.Join("..", "..", "tests", "resources", "a_directory")
+var testDir = "a_directory"
 
 func testEnv(name string) string {
 	return map[string]string{
@@ -17,10 +19,53 @@ func testEnv(name string) string {
 	}[name]
 }
 
+// fakeFS is a minimal in-memory FS for glob expansion tests, so they don't
+// depend on fixtures on disk.
+type fakeFS struct {
+	paths []string
+}
+
+func (f fakeFS) Glob(pattern string) ([]string, error) {
+	var matches []string
+	for _, p := range f.paths {
+		if ok, _ := filepath.Match(pattern, p); ok {
+			matches = append(matches, p)
+		}
+	}
+	return matches, nil
+}
+
+var testFS = fakeFS{paths: []string{
+	path.Join(testDir, "a_file"),
+	path.Join(testDir, "another_file"),
+}}
+
+// fakeExecutor is a minimal in-memory Executor for command substitution
+// tests, so they don't depend on spawning a real subprocess.
+type fakeExecutor struct {
+	output map[string]string
+	err    error
+}
+
+func (e fakeExecutor) Execute(command string) (string, error) {
+	if e.err != nil {
+		return "", e.err
+	}
+
+	return e.output[command], nil
+}
+
+var testExecutor = fakeExecutor{output: map[string]string{
+	"echo hi":       "hi\n",
+	"echo a b":      "a b\n",
+	"printf 'a  b'": "a  b",
+}}
+
 func TestNodeExpand(t *testing.T) {
 	type testCase struct {
 		Name       string
 		Node       Node
+		Executor   Executor
 		Out        []string
 		ExpectsErr bool
 	}
@@ -102,6 +147,37 @@ func TestNodeExpand(t *testing.T) {
 			Out: []string{"*"},
 		},
 
+		// command substitution
+		{
+			Name:     "CommandSubstitutionUnquoted",
+			Node:     &CommandSubstitution{Command: "echo a b"},
+			Executor: testExecutor,
+			Out:      []string{"a", "b"},
+		},
+		{
+			Name:     "CommandSubstitutionQuoted",
+			Node:     &CommandSubstitution{Command: "printf 'a  b'", Quoted: true},
+			Executor: testExecutor,
+			Out:      []string{"a  b"},
+		},
+		{
+			Name:     "CommandSubstitutionTrimsTrailingNewline",
+			Node:     &CommandSubstitution{Command: "echo hi", Quoted: true},
+			Executor: testExecutor,
+			Out:      []string{"hi"},
+		},
+		{
+			Name:       "CommandSubstitutionWithoutExecutor",
+			Node:       &CommandSubstitution{Command: "echo hi"},
+			ExpectsErr: true,
+		},
+		{
+			Name:       "CommandSubstitutionPropagatesExecutorError",
+			Node:       &CommandSubstitution{Command: "echo hi"},
+			Executor:   fakeExecutor{err: fmt.Errorf("boom")},
+			ExpectsErr: true,
+		},
+
 		// failing expansions
 		{
 			Name: "UnterminatedBraceExpansion",
@@ -115,9 +191,11 @@ func TestNodeExpand(t *testing.T) {
 
 	for _, tc := range cases {
 		t.Run(tc.Name, func(t *testing.T) {
-			actual, err := tc.Node.Expand(testEnv)
+			actual, err := tc.Node.Expand(testEnv, testFS, tc.Executor)
 			if !tc.ExpectsErr {
 				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
 			}
 			assert.Equal(t, tc.Out, actual)
 		})
@@ -1,18 +1,25 @@
 package wrash
 
 import (
+	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/samber/lo"
 	"github.com/urfave/cli/v2"
 )
 
-// todo: we only need to specify each builtin's cli.Apps in, out, and err, or use the Sessions in, out, or err not both
+// ansiRed and ansiReset highlight failed (non-zero exit) entries in
+// !!history's plain-text output.
+const (
+	ansiRed   = "\x1b[31m"
+	ansiReset = "\x1b[0m"
+)
 
 func isBuiltin(s string) bool {
 	return strings.HasPrefix(s, "!!")
@@ -71,6 +78,30 @@ func (s *Session) initBuiltins() {
 				Aliases: []string{"s"},
 				Usage:   "include the base command in the output",
 			},
+			&cli.StringFlag{
+				Name:  "since",
+				Usage: "limit shown history entries to those run within the given duration (e.g. 24h, 30m)",
+			},
+			&cli.BoolFlag{
+				Name:  "today",
+				Usage: "limit shown history entries to those run today (overrides --since)",
+			},
+			&cli.BoolFlag{
+				Name:  "failed",
+				Usage: "limit shown history entries to those which exited non-zero",
+			},
+			&cli.StringFlag{
+				Name:  "cwd",
+				Usage: "limit shown history entries to those run in the given working directory",
+			},
+			&cli.BoolFlag{
+				Name:  "json",
+				Usage: "print matched history entries as JSON rather than plain text",
+			},
+			&cli.BoolFlag{
+				Name:  "stats",
+				Usage: "print aggregate count, mean duration, and success rate per base command rather than individual entries",
+			},
 		},
 
 		Reader:    s.stdin,
@@ -104,6 +135,276 @@ func (s *Session) initBuiltins() {
 		Writer:    s.stdout,
 		ErrWriter: s.stderr,
 	}
+
+	s.apps["hub"] = &cli.App{
+		Name:        "hub",
+		Usage:       "hub <update|search|install|list|remove|upgrade>",
+		Description: "fetch, cache, and install completions from a remote hub",
+		Commands: []*cli.Command{
+			{
+				Name:        "update",
+				Usage:       "hub update",
+				Description: "refresh the cached hub index",
+				Action:      s.doHubUpdate,
+			},
+			{
+				Name:        "search",
+				Usage:       "hub search PATTERN",
+				Description: "search the cached hub index",
+				Action:      s.doHubSearch,
+			},
+			{
+				Name:        "install",
+				Usage:       "hub install NAME",
+				Description: "download and install a completion file from the hub",
+				Action:      s.doHubInstall,
+			},
+			{
+				Name:        "list",
+				Usage:       "hub list",
+				Description: "list installed hub entries",
+				Action:      s.doHubList,
+			},
+			{
+				Name:        "remove",
+				Usage:       "hub remove NAME",
+				Description: "remove an installed hub entry",
+				Action:      s.doHubRemove,
+			},
+			{
+				Name:        "upgrade",
+				Usage:       "hub upgrade [NAME]",
+				Description: "reinstall an entry (or all entries) whose index checksum has changed",
+				Action:      s.doHubUpgrade,
+			},
+		},
+
+		Reader:    s.stdin,
+		Writer:    s.stdout,
+		ErrWriter: s.stderr,
+	}
+
+	s.apps["complete"] = &cli.App{
+		Name:        "complete",
+		Usage:       "complete <refresh|clear|show>",
+		Description: "inspect or control the Arg.Cmd completion cache",
+		Commands: []*cli.Command{
+			{
+				Name:        "refresh",
+				Usage:       "complete refresh",
+				Description: "re-run every cached completion command immediately",
+				Action:      s.doCompleteRefresh,
+			},
+			{
+				Name:        "clear",
+				Usage:       "complete clear",
+				Description: "drop all cached completions, in memory and on disk",
+				Action:      s.doCompleteClear,
+			},
+			{
+				Name:        "show",
+				Usage:       "complete show",
+				Description: "list cached completion keys and their state",
+				Action:      s.doCompleteShow,
+			},
+			{
+				Name:        "reload",
+				Usage:       "complete reload",
+				Description: "reload the config-driven completers, the hub's completion file, and aliases from disk",
+				Action:      s.doCompleteReload,
+			},
+		},
+
+		DefaultCommand: "show",
+
+		Reader:    s.stdin,
+		Writer:    s.stdout,
+		ErrWriter: s.stderr,
+	}
+
+	s.apps["reload"] = &cli.App{
+		Name:        "reload",
+		Usage:       "reload",
+		Description: "force-reload the config-driven completers, the hub's completion file, and history from disk",
+		Action:      s.doReload,
+
+		Reader:    s.stdin,
+		Writer:    s.stdout,
+		ErrWriter: s.stderr,
+	}
+
+	s.apps["status"] = &cli.App{
+		Name:        "status",
+		Usage:       "status [on|off]",
+		Description: "show or toggle the live status region shown while commands run",
+		Action:      s.doStatus,
+
+		Reader:    s.stdin,
+		Writer:    s.stdout,
+		ErrWriter: s.stderr,
+	}
+}
+
+// doReload force-reloads the config-driven completers, the hub's per-Base
+// completion file, and the history file from disk immediately, without
+// waiting on watchFiles' debounce.
+func (s *Session) doReload(ctx *cli.Context) error {
+	s.reloadHubCompletion()
+	s.reloadHistory()
+
+	fmt.Fprintf(ctx.App.Writer, "reloaded config from %s\n", s.configPath)
+	if path := s.history.path; path != "" {
+		fmt.Fprintf(ctx.App.Writer, "reloaded history from %s\n", path)
+	}
+
+	return nil
+}
+
+func (s *Session) doStatus(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		state := "off"
+		if s.statusEnabled {
+			state = "on"
+		}
+		fmt.Fprintln(ctx.App.Writer, state)
+		return nil
+	}
+
+	switch arg := ctx.Args().First(); arg {
+	case "on":
+		s.statusEnabled = true
+	case "off":
+		s.statusEnabled = false
+	default:
+		return fmt.Errorf("unknown status state: %s", arg)
+	}
+
+	return nil
+}
+
+func (s *Session) doCompleteRefresh(ctx *cli.Context) error {
+	n := completionCache.refreshAll()
+	fmt.Fprintf(ctx.App.Writer, "refreshed %d cached completion(s)\n", n)
+	return nil
+}
+
+func (s *Session) doCompleteClear(ctx *cli.Context) error {
+	completionCache.clear()
+	fmt.Fprintln(ctx.App.Writer, "completion cache cleared")
+	return nil
+}
+
+func (s *Session) doCompleteShow(ctx *cli.Context) error {
+	for _, summary := range completionCache.list() {
+		fmt.Fprintf(ctx.App.Writer, "%s (cmd=%s) - %d suggestion(s), expires %s\n",
+			summary.Key, strings.Join(summary.Cmd, " "), summary.Count, summary.ExpiresAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func (s *Session) doCompleteReload(ctx *cli.Context) error {
+	s.reloadHubCompletion()
+	fmt.Fprintf(ctx.App.Writer, "reloaded config from %s\n", s.configPath)
+	return nil
+}
+
+func (s *Session) doHubUpdate(ctx *cli.Context) error {
+	if err := s.hub.Update(); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(ctx.App.Writer, "hub index updated")
+	return nil
+}
+
+func (s *Session) doHubSearch(ctx *cli.Context) error {
+	pattern := ".*"
+	if ctx.Args().Present() {
+		pattern = ctx.Args().First()
+	}
+
+	entries, err := s.hub.Search(pattern)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		fmt.Fprintf(ctx.App.Writer, "%s (%s) - %s\n", entry.Name, entry.Version, entry.Description)
+	}
+
+	return nil
+}
+
+func (s *Session) doHubInstall(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return fmt.Errorf("no entry name given")
+	}
+
+	if err := s.hub.Install(ctx.Args().First()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.App.Writer, "installed '%s'\n", ctx.Args().First())
+	return nil
+}
+
+func (s *Session) doHubList(ctx *cli.Context) error {
+	installed, err := s.hub.List()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range installed {
+		fmt.Fprintf(ctx.App.Writer, "%s (%s)\n", entry.Name, entry.Version)
+	}
+
+	return nil
+}
+
+func (s *Session) doHubRemove(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return fmt.Errorf("no entry name given")
+	}
+
+	if err := s.hub.Remove(ctx.Args().First()); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.App.Writer, "removed '%s'\n", ctx.Args().First())
+	return nil
+}
+
+func (s *Session) doHubUpgrade(ctx *cli.Context) error {
+	if ctx.Args().Present() {
+		name := ctx.Args().First()
+
+		upgraded, err := s.hub.Upgrade(name)
+		if err != nil {
+			return err
+		}
+
+		if upgraded {
+			fmt.Fprintf(ctx.App.Writer, "upgraded '%s'\n", name)
+		} else {
+			fmt.Fprintf(ctx.App.Writer, "'%s' is already up to date\n", name)
+		}
+
+		return nil
+	}
+
+	upgraded, err := s.hub.UpgradeAll()
+	if err != nil {
+		return err
+	}
+
+	if len(upgraded) == 0 {
+		fmt.Fprintln(ctx.App.Writer, "everything is already up to date")
+		return nil
+	}
+
+	fmt.Fprintf(ctx.App.Writer, "upgraded: %s\n", strings.Join(upgraded, ", "))
+	return nil
 }
 
 func (s *Session) doCd(ctx *cli.Context) error {
@@ -113,7 +414,7 @@ func (s *Session) doCd(ctx *cli.Context) error {
 	var err error
 
 	if args.Len() == 0 {
-		target, err = os.UserHomeDir()
+		target, err = s.fs.UserHomeDir()
 		if err != nil {
 			return fmt.Errorf("could not determine user's home dieectory: %w", err)
 		}
@@ -123,7 +424,7 @@ func (s *Session) doCd(ctx *cli.Context) error {
 		return fmt.Errorf("too many arguments")
 	}
 
-	if err := os.Chdir(target); err != nil {
+	if err := s.fs.Chdir(target); err != nil {
 		return fmt.Errorf("could not change directory: %s", err)
 	}
 
@@ -152,7 +453,7 @@ func (s *Session) doExit(ctx *cli.Context) error {
 	return nil
 }
 
-func (s *Session) doHelp(*cli.Context) error {
+func (s *Session) doHelp(ctx *cli.Context) error {
 	if s.apps == nil {
 		return fmt.Errorf("apps was not initialized")
 	}
@@ -175,7 +476,7 @@ Below is a list of supported builtins, pass '--help' to any of them for more inf
 		helpMsg += fmt.Sprintf(format, name, app.Description)
 	}
 
-	fmt.Fprintln(s.stdout, helpMsg)
+	fmt.Fprintln(ctx.App.Writer, helpMsg)
 
 	return nil
 }
@@ -194,24 +495,107 @@ func (s *Session) doHistory(ctx *cli.Context) error {
 
 	n := ctx.Int("number")
 	show := ctx.Bool("show")
+	failed := ctx.Bool("failed")
+	cwd := ctx.String("cwd")
+
+	var since time.Time
+	if ctx.Bool("today") {
+		now := time.Now()
+		since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	} else if rawSince := ctx.String("since"); rawSince != "" {
+		d, err := time.ParseDuration(rawSince)
+		if err != nil {
+			return fmt.Errorf("could not parse --since duration: %s", err)
+		}
+		since = time.Now().Add(-d)
+	}
 
-	matched := lo.FilterMap(s.history.entries[:len(s.history.entries)-1], func(entry *Entry, _ int) (string, bool) {
+	matchedEntries := lo.Filter(s.history.entries[:len(s.history.entries)-1], func(entry *Entry, _ int) bool {
 		if !(entry.Base == s.Base && pattern.MatchString(entry.Cmd)) {
-			return "", false
+			return false
 		}
 
-		if show {
-			return entry.Base + " " + entry.Cmd, true
+		if failed && entry.Exit == 0 {
+			return false
 		}
 
-		return entry.Cmd, true
+		if cwd != "" && entry.Cwd != cwd {
+			return false
+		}
+
+		if !since.IsZero() && entry.Ts.Before(since) {
+			return false
+		}
+
+		return true
 	})
 
-	if n > 0 && n < len(matched) {
-		matched = matched[len(matched)-n:]
+	if n > 0 && n < len(matchedEntries) {
+		matchedEntries = matchedEntries[len(matchedEntries)-n:]
+	}
+
+	if ctx.Bool("stats") {
+		return s.printHistoryStats(ctx.App.Writer, matchedEntries)
+	}
+
+	if ctx.Bool("json") {
+		for _, entry := range matchedEntries {
+			data, err := json.Marshal(entry)
+			if err != nil {
+				return fmt.Errorf("could not marshal history entry: %w", err)
+			}
+
+			fmt.Fprintln(ctx.App.Writer, string(data))
+		}
+
+		return nil
 	}
 
-	fmt.Fprintln(s.stdout, strings.Join(matched, "\n"))
+	matched := lo.Map(matchedEntries, func(entry *Entry, _ int) string {
+		line := entry.Cmd
+		if show {
+			line = entry.Base + " " + line
+		}
+
+		if entry.Exit != 0 {
+			line = ansiRed + line + ansiReset
+		}
+
+		return line
+	})
+
+	fmt.Fprintln(ctx.App.Writer, strings.Join(matched, "\n"))
+
+	return nil
+}
+
+// printHistoryStats prints, per distinct base command, the number of
+// matched entries, their mean duration, and the fraction that exited zero.
+func (s *Session) printHistoryStats(w io.Writer, entries []*Entry) error {
+	grouped := lo.GroupBy(entries, func(entry *Entry) string {
+		return entry.Base
+	})
+
+	bases := sort.StringSlice(lo.Keys(grouped))
+	sort.Sort(bases)
+
+	for _, base := range bases {
+		group := grouped[base]
+
+		var totalMs int64
+		var successes int
+		for _, entry := range group {
+			totalMs += entry.DurationMs
+			if entry.Exit == 0 {
+				successes++
+			}
+		}
+
+		meanMs := float64(totalMs) / float64(len(group))
+		successRate := float64(successes) / float64(len(group))
+
+		fmt.Fprintf(w, "%s: count=%d mean=%.0fms success=%.0f%%\n", base, len(group), meanMs, successRate*100)
+	}
 
 	return nil
 }
@@ -237,7 +621,7 @@ func (s *Session) doEnv(ctx *cli.Context) error {
 		sort.Sort(keys)
 
 		for _, key := range keys {
-			fmt.Fprintf(s.stdout, "%s='%s'\n", key, s.environ[key])
+			fmt.Fprintf(ctx.App.Writer, "%s='%s'\n", key, s.environ[key])
 		}
 		return nil
 	default:
@@ -1,9 +1,6 @@
 package wrash
 
 import (
-	"os"
-	"path/filepath"
-
 	prompt "github.com/joshmeranda/go-prompt"
 	"github.com/samber/lo"
 )
@@ -12,19 +9,19 @@ import (
 
 // todo: ideally we'd be able to show the completions with oonly the basenames (prompt.Suggeestion previews)
 // todo: don't cleanup the './' in the path
-func getFilesWithPrefix(prefix string) []prompt.Suggest {
+func getFilesWithPrefix(fsys FS, prefix string) []prompt.Suggest {
 	if prefix == "" {
 		return []prompt.Suggest{}
 	}
 
-	paths, err := filepath.Glob(prefix + "*")
+	paths, err := fsys.Glob(prefix + "*")
 	if err != nil {
 		return []prompt.Suggest{}
 	}
 
 	return lo.FilterMap(paths, func(path string, _ int) (prompt.Suggest, bool) {
 		// todo: ideally we woulnd't need to do make another syscall just to get the info
-		info, err := os.Stat(path)
+		info, err := fsys.Stat(path)
 		if err != nil {
 			return prompt.Suggest{}, false
 		}
@@ -39,6 +36,6 @@ func getFilesWithPrefix(prefix string) []prompt.Suggest {
 	})
 }
 
-func fileCompleter(doc prompt.Document) []prompt.Suggest {
-	return getFilesWithPrefix(doc.GetWordBeforeCursor())
+func fileCompleter(fsys FS, doc prompt.Document) []prompt.Suggest {
+	return getFilesWithPrefix(fsys, doc.GetWordBeforeCursor())
 }
@@ -1,20 +1,20 @@
 package wrash
 
 import (
-	"os"
 	"testing"
+	"testing/fstest"
 
 	prompt "github.com/joshmeranda/go-prompt"
 	"github.com/stretchr/testify/assert"
-	"github.com/stretchr/testify/require"
 )
 
 func TestFileComplete(t *testing.T) {
-	old, err := os.Getwd()
-	require.NoError(t, err)
-
-	require.NoError(t, os.Chdir("../tests"))
-	defer os.Chdir(old)
+	fsys := NewMemFS(fstest.MapFS{
+		"resources/a_directory/a_file":           {},
+		"resources/a_directory/another_file":     {},
+		"resources/a_directory/some_other_file":  {},
+		"resources/a_directory/directory/nested": {},
+	})
 
 	testCases := []struct {
 		Name     string
@@ -40,7 +40,7 @@ func TestFileComplete(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.Name, func(t *testing.T) {
-			actual := getFilesWithPrefix(tc.Text)
+			actual := getFilesWithPrefix(fsys, tc.Text)
 			assert.Equal(t, tc.Expected, actual)
 		})
 	}
@@ -0,0 +1,101 @@
+package wrash
+
+import (
+	"strings"
+	"sync"
+
+	prompt "github.com/joshmeranda/go-prompt"
+)
+
+// CompleterRegistry maps a base command to the prompt.Completer used while
+// completing its arguments, and each base's alias table. It is safe for
+// concurrent use, since loadConfig may run from a config-file watcher
+// goroutine while completer and Alias are called from the prompt loop.
+type CompleterRegistry struct {
+	mu sync.RWMutex
+
+	completers map[string]prompt.Completer
+	aliases    map[string]map[string]string
+
+	fs FS
+}
+
+// NewCompleterRegistry builds an empty registry whose fallback completer
+// (used for any base with no registered or config-loaded completer) lists
+// files from fsys.
+func NewCompleterRegistry(fsys FS) *CompleterRegistry {
+	return &CompleterRegistry{
+		completers: make(map[string]prompt.Completer),
+		aliases:    make(map[string]map[string]string),
+		fs:         fsys,
+	}
+}
+
+// Register associates c with base, so embedders can wire up completers for a
+// wrapped command programmatically instead of (or in addition to) through a
+// config file. It overrides whatever completer base previously had,
+// including one loaded from config.
+func (r *CompleterRegistry) Register(base string, c prompt.Completer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.completers[base] = c
+}
+
+// Completer returns the registered completer for base, or fileCompleter if
+// none was registered or loaded from config.
+func (r *CompleterRegistry) Completer(base string) prompt.Completer {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if c, found := r.completers[base]; found {
+		return c
+	}
+
+	return func(doc prompt.Document) []prompt.Suggest {
+		return fileCompleter(r.fs, doc)
+	}
+}
+
+// Alias reports the expansion of token under base's alias table, if any.
+func (r *CompleterRegistry) Alias(base string, token string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	expansion, found := r.aliases[base][token]
+	return expansion, found
+}
+
+// loadConfig replaces the registry's config-derived completers and aliases
+// with cfg's. Completers added through Register are left in place, and are
+// only overridden if cfg also declares a completer for the same base.
+func (r *CompleterRegistry) loadConfig(cfg *Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for base, suggestion := range cfg.Commands {
+		r.completers[base] = suggestorCompleter(suggestion)
+	}
+
+	r.aliases = cfg.Aliases
+}
+
+// suggestorCompleter adapts a Suggestor (a CommandSuggestion's tree of
+// static word lists, path filters, subcommand trees, and "run this helper"
+// completers) into a prompt.Completer.
+func suggestorCompleter(s Suggestor) prompt.Completer {
+	return func(doc prompt.Document) []prompt.Suggest {
+		return suggestFromText(s, doc.TextBeforeCursor())
+	}
+}
+
+// suggestFromText splits text into whitespace-separated fields and asks s to
+// suggest completions for it, treating a trailing unfinished word (text not
+// ending in whitespace) as the value to be completed rather than a
+// completed argument.
+func suggestFromText(s Suggestor, text string) []prompt.Suggest {
+	fields := strings.Fields(text)
+	completeLast := len(fields) > 0 && !strings.HasSuffix(text, " ")
+
+	return s.Suggest(fields, completeLast)
+}
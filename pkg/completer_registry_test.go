@@ -0,0 +1,94 @@
+package wrash
+
+import (
+	"testing"
+
+	prompt "github.com/joshmeranda/go-prompt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleterRegistryFallsBackToFileCompleter(t *testing.T) {
+	r := NewCompleterRegistry(OsFS{})
+
+	assert.Equal(t, []prompt.Suggest{}, r.Completer("git")(prompt.Document{}))
+}
+
+func TestCompleterRegistryRegister(t *testing.T) {
+	r := NewCompleterRegistry(OsFS{})
+
+	called := false
+	r.Register("git", func(prompt.Document) []prompt.Suggest {
+		called = true
+		return nil
+	})
+
+	r.Completer("git")(prompt.Document{})
+	assert.True(t, called)
+
+	_, found := r.Alias("git", "co")
+	assert.False(t, found)
+}
+
+func TestCompleterRegistryLoadConfig(t *testing.T) {
+	r := NewCompleterRegistry(OsFS{})
+
+	r.loadConfig(&Config{
+		Commands: map[string]*CommandSuggestion{
+			"git": {
+				SubCommands: map[string]CommandSuggestion{
+					"status": {Description: "show status"},
+					"log":    {Description: "show log"},
+				},
+			},
+		},
+		Aliases: map[string]map[string]string{
+			"git": {"co": "checkout"},
+		},
+	})
+
+	suggestions := r.Completer("git")(prompt.Document{})
+	assert.ElementsMatch(t, []prompt.Suggest{
+		{Text: "status", Description: "show status"},
+		{Text: "log", Description: "show log"},
+	}, suggestions)
+
+	alias, found := r.Alias("git", "co")
+	assert.True(t, found)
+	assert.Equal(t, "checkout", alias)
+
+	_, found = r.Alias("git", "unknown")
+	assert.False(t, found)
+}
+
+func TestSuggestFromText(t *testing.T) {
+	s := &CommandSuggestion{
+		SubCommands: map[string]CommandSuggestion{
+			"status": {Description: "show status"},
+			"stash":  {Description: "stash changes"},
+		},
+	}
+
+	assert.Equal(t, []prompt.Suggest{
+		{Text: "stash", Description: "stash changes"},
+		{Text: "status", Description: "show status"},
+	}, suggestFromText(s, "sta"))
+
+	assert.Empty(t, suggestFromText(s, "status "))
+}
+
+func TestCompleterRegistryRegisterOverridesConfig(t *testing.T) {
+	r := NewCompleterRegistry(OsFS{})
+
+	r.loadConfig(&Config{
+		Commands: map[string]*CommandSuggestion{
+			"git": {Args: Arg{Choices: []string{"status"}}},
+		},
+	})
+
+	r.Register("git", func(prompt.Document) []prompt.Suggest {
+		return []prompt.Suggest{{Text: "overridden"}}
+	})
+
+	suggestions := r.Completer("git")(prompt.Document{})
+	assert.Equal(t, []prompt.Suggest{{Text: "overridden"}}, suggestions)
+}
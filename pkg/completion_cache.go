@@ -0,0 +1,324 @@
+package wrash
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/joshmeranda/go-prompt"
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// Duration wraps time.Duration so CacheConfig can be configured with plain
+// strings like "30s" in YAML.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	if value.Value == "" {
+		*d = 0
+		return nil
+	}
+
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("could not parse duration %q: %w", value.Value, err)
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+const (
+	defaultCacheTTL      = 30 * time.Second
+	defaultCacheDeadline = 150 * time.Millisecond
+	defaultCacheKey      = "{{ .Base }}:{{ .Args }}"
+)
+
+// CacheConfig enables result caching for an Arg.Cmd completion producer, so
+// slow producers (e.g. `kubectl get pods -o name`) aren't re-run on every
+// keystroke.
+type CacheConfig struct {
+	TTL      Duration `yaml:"ttl"`
+	Key      string   `yaml:"key"`
+	Refresh  string   `yaml:"refresh"`
+	Deadline Duration `yaml:"deadline"`
+}
+
+func (c *CacheConfig) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return defaultCacheTTL
+	}
+
+	return time.Duration(c.TTL)
+}
+
+func (c *CacheConfig) deadline() time.Duration {
+	if c.Deadline <= 0 {
+		return defaultCacheDeadline
+	}
+
+	return time.Duration(c.Deadline)
+}
+
+// sync reports whether a stale cache entry must be refreshed before being
+// returned, rather than served immediately alongside a background refresh.
+func (c *CacheConfig) sync() bool {
+	return c.Refresh == "sync"
+}
+
+func (c *CacheConfig) renderKey(cmd []string, arg string) (string, error) {
+	text := c.Key
+	if text == "" {
+		text = defaultCacheKey
+	}
+
+	tmpl, err := template.New("cache-key").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("could not parse cache key template: %w", err)
+	}
+
+	data := struct {
+		Base string
+		Args string
+	}{
+		Base: strings.Join(cmd, " "),
+		Args: arg,
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("could not render cache key template: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// cacheEntry is both the in-memory and on-disk representation of a single
+// completionCache record.
+type cacheEntry struct {
+	Cmd         []string         `json:"cmd"`
+	Arg         string           `json:"arg"`
+	TTL         time.Duration    `json:"ttl"`
+	ExpiresAt   time.Time        `json:"expires_at"`
+	Suggestions []prompt.Suggest `json:"suggestions"`
+}
+
+type cacheSummary struct {
+	Key       string
+	Cmd       []string
+	Count     int
+	ExpiresAt time.Time
+}
+
+// completionCacheStore is the package-level cache backing every Arg with a
+// Cache config, persisted under ~/.wrash/cache so results survive session
+// restarts.
+type completionCacheStore struct {
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+	dir     string
+}
+
+func newCompletionCacheStore() *completionCacheStore {
+	home, _ := os.UserHomeDir()
+
+	return &completionCacheStore{
+		entries: make(map[string]*cacheEntry),
+		dir:     filepath.Join(home, ".wrash", "cache"),
+	}
+}
+
+var completionCache = newCompletionCacheStore()
+
+func (c *completionCacheStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the entry cached for key (checking disk if it isn't already
+// loaded in memory) and whether it is still within its TTL. The returned
+// bool is false only when no entry exists anywhere.
+func (c *completionCacheStore) get(key string) (entry *cacheEntry, fresh bool, found bool) {
+	c.mu.Lock()
+	entry, found = c.entries[key]
+	c.mu.Unlock()
+
+	if !found {
+		entry = c.loadFromDisk(key)
+		if entry == nil {
+			return nil, false, false
+		}
+
+		c.mu.Lock()
+		c.entries[key] = entry
+		c.mu.Unlock()
+
+		found = true
+	}
+
+	return entry, time.Now().Before(entry.ExpiresAt), found
+}
+
+func (c *completionCacheStore) loadFromDisk(key string) *cacheEntry {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil
+	}
+
+	return &entry
+}
+
+func (c *completionCacheStore) set(key string, cmd []string, arg string, suggestions []prompt.Suggest, ttl time.Duration) {
+	entry := &cacheEntry{
+		Cmd:         cmd,
+		Arg:         arg,
+		TTL:         ttl,
+		ExpiresAt:   time.Now().Add(ttl),
+		Suggestions: suggestions,
+	}
+
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	c.persist(key, entry)
+}
+
+func (c *completionCacheStore) persist(key string, entry *cacheEntry) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	_ = os.WriteFile(c.path(key), data, 0644)
+}
+
+// clear drops every cached entry, in memory and on disk.
+func (c *completionCacheStore) clear() {
+	c.mu.Lock()
+	c.entries = make(map[string]*cacheEntry)
+	c.mu.Unlock()
+
+	os.RemoveAll(c.dir)
+}
+
+// refreshAll re-runs the producer command behind every cached entry,
+// returning the number refreshed.
+func (c *completionCacheStore) refreshAll() int {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.entries))
+	for key := range c.entries {
+		keys = append(keys, key)
+	}
+	c.mu.Unlock()
+
+	refreshed := 0
+	for _, key := range keys {
+		c.mu.Lock()
+		entry := c.entries[key]
+		c.mu.Unlock()
+
+		if entry == nil || len(entry.Cmd) == 0 {
+			continue
+		}
+
+		arg := &Arg{Cmd: entry.Cmd}
+		c.set(key, entry.Cmd, entry.Arg, arg.runCmd(entry.Arg), entry.TTL)
+		refreshed++
+	}
+
+	return refreshed
+}
+
+func (c *completionCacheStore) list() []cacheSummary {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	summaries := make([]cacheSummary, 0, len(c.entries))
+	for key, entry := range c.entries {
+		summaries = append(summaries, cacheSummary{
+			Key:       key,
+			Cmd:       entry.Cmd,
+			Count:     len(entry.Suggestions),
+			ExpiresAt: entry.ExpiresAt,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Key < summaries[j].Key
+	})
+
+	return summaries
+}
+
+// suggestCached serves o.Cmd's suggestions through completionCache: a fresh
+// entry returns immediately; a stale-but-present entry returns immediately
+// while a refresh runs in the background (unless Cache.Refresh is "sync");
+// a miss blocks for up to Cache.deadline() before falling back to no
+// suggestions, while the refresh goroutine keeps running to populate the
+// cache for the next call.
+func (o *Arg) suggestCached(arg string) []prompt.Suggest {
+	key, err := o.Cache.renderKey(o.Cmd, arg)
+	if err != nil {
+		return o.runCmd(arg)
+	}
+
+	if entry, fresh, found := completionCache.get(key); found && (fresh || !o.Cache.sync()) {
+		if !fresh {
+			go o.refresh(key, arg)
+		}
+
+		return entry.Suggestions
+	}
+
+	result := make(chan []prompt.Suggest, 1)
+	go func() {
+		result <- o.refresh(key, arg)
+	}()
+
+	select {
+	case suggestions := <-result:
+		return suggestions
+	case <-time.After(o.Cache.deadline()):
+		return []prompt.Suggest{}
+	}
+}
+
+func (o *Arg) refresh(key string, arg string) []prompt.Suggest {
+	suggestions := o.runCmd(arg)
+	completionCache.set(key, o.Cmd, arg, suggestions, o.Cache.ttl())
+	return suggestions
+}
+
+// runCmd shells out to o.Cmd directly, bypassing the cache.
+func (o *Arg) runCmd(arg string) []prompt.Suggest {
+	out, err := exec.Command(o.Cmd[0], o.Cmd[1:]...).Output()
+	if err != nil {
+		return []prompt.Suggest{}
+	}
+
+	return lo.FilterMap(strings.Split(string(out), "\n"), func(text string, _ int) (prompt.Suggest, bool) {
+		return prompt.Suggest{
+			Text: text,
+		}, strings.HasPrefix(text, arg)
+	})
+}
@@ -0,0 +1,93 @@
+package wrash
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	prompt "github.com/joshmeranda/go-prompt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArgSuggestCached(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	completionCache = newCompletionCacheStore()
+
+	arg := &Arg{
+		Cmd: []string{"sh", "-c", "sleep 0.2; echo sleepy"},
+		Cache: &CacheConfig{
+			TTL:      Duration(time.Minute),
+			Deadline: Duration(time.Second),
+		},
+	}
+
+	start := time.Now()
+	suggestions := arg.Suggest("")
+	first := time.Since(start)
+
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, "sleepy", suggestions[0].Text)
+	assert.GreaterOrEqual(t, first, 150*time.Millisecond)
+
+	start = time.Now()
+	suggestions = arg.Suggest("")
+	second := time.Since(start)
+
+	require.NotEmpty(t, suggestions)
+	assert.Equal(t, "sleepy", suggestions[0].Text)
+	assert.Less(t, second, 50*time.Millisecond)
+}
+
+func TestArgSuggestCachedMissTimesOut(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	completionCache = newCompletionCacheStore()
+
+	arg := &Arg{
+		Cmd: []string{"sh", "-c", "sleep 1; echo slow"},
+		Cache: &CacheConfig{
+			TTL:      Duration(time.Minute),
+			Deadline: Duration(50 * time.Millisecond),
+		},
+	}
+
+	start := time.Now()
+	suggestions := arg.Suggest("")
+	elapsed := time.Since(start)
+
+	assert.Empty(t, suggestions)
+	assert.Less(t, elapsed, 500*time.Millisecond)
+}
+
+func TestCompletionCacheStorePersistsToDisk(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	completionCache = newCompletionCacheStore()
+
+	key := "git:status"
+	completionCache.set(key, []string{"git", "status"}, "", []prompt.Suggest{{Text: "a"}}, time.Minute)
+
+	path := completionCache.path(key)
+	_, err := os.Stat(path)
+	require.NoError(t, err)
+
+	reloaded := newCompletionCacheStore()
+	entry, fresh, found := reloaded.get(key)
+	require.True(t, found)
+	assert.True(t, fresh)
+	assert.Equal(t, []string{"git", "status"}, entry.Cmd)
+}
+
+func TestCompletionCacheStoreClear(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	completionCache = newCompletionCacheStore()
+
+	completionCache.set("k", []string{"echo"}, "", []prompt.Suggest{{Text: "a"}}, time.Minute)
+	completionCache.clear()
+
+	_, _, found := completionCache.get("k")
+	assert.False(t, found)
+}
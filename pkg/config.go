@@ -0,0 +1,57 @@
+package wrash
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the shape of wrash's user config file: per-base completers
+// (CommandSuggestion trees, the same format LoadSuggestions reads) and
+// per-base alias tables that expand before dispatch.
+type Config struct {
+	// Commands maps a base command (e.g. "git") to the completer tree used
+	// while completing arguments to that command.
+	Commands map[string]*CommandSuggestion `yaml:"commands"`
+
+	// Aliases maps a base command to a table of short name -> expansion,
+	// e.g. {"git": {"co": "checkout"}} expands a leading "co" to "checkout"
+	// before an external command for base "git" is run.
+	Aliases map[string]map[string]string `yaml:"aliases"`
+}
+
+// LoadConfig reads and parses a Config from p.
+func LoadConfig(p string) (*Config, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file %q: %w", p, err)
+	}
+
+	cfg := &Config{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("could not unmarshal config file %q: %w", p, err)
+	}
+
+	return cfg, nil
+}
+
+// EnvConfigHome is the XDG base directory variable wrash's default config
+// path is resolved relative to.
+const EnvConfigHome = "XDG_CONFIG_HOME"
+
+// DefaultConfigPath returns $XDG_CONFIG_HOME/wrash/config.yaml, falling back
+// to $HOME/.config/wrash/config.yaml if XDG_CONFIG_HOME isn't set.
+func DefaultConfigPath() (string, error) {
+	if dir := os.Getenv(EnvConfigHome); dir != "" {
+		return filepath.Join(dir, "wrash", "config.yaml"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not determine user home directory: %w", err)
+	}
+
+	return filepath.Join(home, ".config", "wrash", "config.yaml"), nil
+}
@@ -0,0 +1,44 @@
+package wrash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+commands:
+  git:
+    description: git
+    args:
+      choices: ["status", "log"]
+aliases:
+  git:
+    co: checkout
+`), 0644))
+
+	cfg, err := LoadConfig(path)
+	require.NoError(t, err)
+
+	require.Contains(t, cfg.Commands, "git")
+	assert.Equal(t, []string{"status", "log"}, cfg.Commands["git"].Args.Choices)
+	assert.Equal(t, "checkout", cfg.Aliases["git"]["co"])
+}
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	_, err := LoadConfig(filepath.Join(t.TempDir(), "nonexistent.yaml"))
+	assert.Error(t, err)
+}
+
+func TestDefaultConfigPath(t *testing.T) {
+	t.Setenv(EnvConfigHome, "/some/xdg/config/home")
+
+	path, err := DefaultConfigPath()
+	require.NoError(t, err)
+	assert.Equal(t, "/some/xdg/config/home/wrash/config.yaml", path)
+}
@@ -0,0 +1,32 @@
+package wrash
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// FS abstracts the filesystem operations wrash needs for glob expansion,
+// `cd`, and file completion, so embedders can sandbox a Session to a virtual
+// or chroot-like subtree without forking the module, and so tests can run
+// against an in-memory tree instead of fixtures on disk.
+type FS interface {
+	Glob(pattern string) ([]string, error)
+	Stat(name string) (os.FileInfo, error)
+	Chdir(dir string) error
+	Getwd() (string, error)
+	UserHomeDir() (string, error)
+}
+
+// OsFS is the default FS, delegating directly to the os and path/filepath
+// packages.
+type OsFS struct{}
+
+func (OsFS) Glob(pattern string) ([]string, error) { return filepath.Glob(pattern) }
+
+func (OsFS) Stat(name string) (os.FileInfo, error) { return os.Stat(name) }
+
+func (OsFS) Chdir(dir string) error { return os.Chdir(dir) }
+
+func (OsFS) Getwd() (string, error) { return os.Getwd() }
+
+func (OsFS) UserHomeDir() (string, error) { return os.UserHomeDir() }
@@ -4,14 +4,27 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	prompt "github.com/joshmeranda/go-prompt"
 	"gopkg.in/yaml.v3"
 )
 
 type Entry struct {
-	Base string
-	Cmd  string
+	Base string `yaml:"base" json:"base"`
+	Cmd  string `yaml:"cmd" json:"cmd"`
+
+	// Seq, Ts, Exit, DurationMs, Cwd, and EnvHash are only populated for
+	// entries recorded through NewJSONLHistory; the legacy YAML format never
+	// wrote them, so they're left out of yaml (de)serialization entirely.
+	Seq        int64     `yaml:"-" json:"seq"`
+	Ts         time.Time `yaml:"-" json:"ts,omitempty"`
+	Exit       int       `yaml:"-" json:"exit"`
+	DurationMs int64     `yaml:"-" json:"duration_ms"`
+	Cwd        string    `yaml:"-" json:"cwd,omitempty"`
+	EnvHash    string    `yaml:"-" json:"env_hash,omitempty"`
 
 	changes string
 }
@@ -21,8 +34,36 @@ type history struct {
 
 	current int
 	base    string
+	nextSeq int64
 
 	w io.Writer
+
+	// path is the on-disk JSONL file or SQLite database backing this
+	// history (empty for a plain in-memory history, e.g. in tests). Session
+	// uses it to watch the file for external edits and call Reload.
+	path string
+
+	// jsonl is only set by NewJSONLHistory; when set, Sync reconciles
+	// entries against the file on disk rather than rewriting it outright.
+	jsonl *jsonlWriter
+
+	// sqlite is only set by NewSQLiteHistory; when set, Sync upserts
+	// entries into the database rather than rewriting a file outright (and
+	// takes priority over jsonl, though the two are never set together).
+	sqlite *sqliteStore
+
+	// searching, query, matches, matchIdx, savedText, and searchDisplay hold
+	// the state of an in-progress reverse incremental search (Ctrl-R); see
+	// startSearch. scopeCwd and cwd additionally restrict that search to
+	// entries run in cwd, toggled by toggleScope (Ctrl-T).
+	searching     bool
+	query         string
+	matches       []*Entry
+	matchIdx      int
+	savedText     string
+	searchDisplay string
+	scopeCwd      bool
+	cwd           string
 }
 
 type WriterFunc func([]byte) (int, error)
@@ -53,11 +94,25 @@ func NewHistory(base string, w io.Writer, entries []*Entry) prompt.History {
 
 		current: len(newEntries) - 1,
 		base:    base,
+		nextSeq: nextSeqAfter(entries),
 
 		w: w,
 	}
 }
 
+// nextSeqAfter returns the next Seq to assign given a set of already-loaded
+// entries, i.e. one greater than the highest Seq among them (or 0 if empty).
+func nextSeqAfter(entries []*Entry) int64 {
+	var next int64
+	for _, entry := range entries {
+		if entry.Seq >= next {
+			next = entry.Seq + 1
+		}
+	}
+
+	return next
+}
+
 func (h *history) Add(inputs ...string) {
 	h.entries = h.entries[:len(h.entries)-1]
 
@@ -79,7 +134,9 @@ func (h *history) Add(inputs ...string) {
 		h.entries = append(h.entries, &Entry{
 			Base: base,
 			Cmd:  s,
+			Seq:  h.nextSeq,
 		})
+		h.nextSeq++
 	}
 	h.entries = append(h.entries, &Entry{
 		Base: h.base,
@@ -91,6 +148,180 @@ func (h *history) Clear() {
 	for _, entry := range h.entries {
 		entry.changes = ""
 	}
+
+	h.searching = false
+	h.query = ""
+	h.matches = nil
+	h.matchIdx = 0
+	h.savedText = ""
+	h.searchDisplay = ""
+	h.scopeCwd = false
+	h.cwd = ""
+}
+
+// Search returns the entries visible to base (matching base, or any !!
+// builtin invocation, the same scoping Older and Newer apply) whose Cmd
+// contains query, most-recent-first. An empty query matches every entry.
+func (h *history) Search(query string, base string) []*Entry {
+	var matches []*Entry
+
+	for i := len(h.entries) - 2; i >= 0; i-- {
+		entry := h.entries[i]
+		if entry.Cmd == "" {
+			continue
+		}
+		if entry.Base != base && !isBuiltin(entry.Cmd) {
+			continue
+		}
+		if query != "" && !strings.Contains(entry.Cmd, query) {
+			continue
+		}
+
+		matches = append(matches, entry)
+	}
+
+	return matches
+}
+
+// SearchCwd behaves like Search, but further restricts matches to entries
+// recorded in cwd.
+func (h *history) SearchCwd(query string, base string, cwd string) []*Entry {
+	var matches []*Entry
+	for _, entry := range h.Search(query, base) {
+		if entry.Cwd == cwd {
+			matches = append(matches, entry)
+		}
+	}
+
+	return matches
+}
+
+// runSearch refreshes h.matches for the current query, base, and scope
+// (global, or restricted to h.cwd if scopeCwd is set).
+func (h *history) runSearch() {
+	if h.scopeCwd {
+		h.matches = h.SearchCwd(h.query, h.base, h.cwd)
+	} else {
+		h.matches = h.Search(h.query, h.base)
+	}
+}
+
+// startSearch is bound to Ctrl-R. The first press enters reverse incremental
+// search mode starting from the current buffer text as the seed query;
+// repeated presses step further back through the matches for the current
+// query.
+func (h *history) startSearch(buf *prompt.Buffer) {
+	if !h.searching {
+		h.searching = true
+		h.savedText = buf.Text()
+		h.query = h.savedText
+		h.matchIdx = 0
+		h.scopeCwd = false
+	} else {
+		h.matchIdx++
+	}
+
+	h.runSearch()
+	h.showMatch(buf)
+}
+
+// toggleScope is bound to Ctrl-T while an incremental search is active,
+// switching between searching every entry and restricting matches to cwd.
+// It's a no-op outside of a search.
+func (h *history) toggleScope(buf *prompt.Buffer, cwd string) {
+	if !h.searching {
+		return
+	}
+
+	h.scopeCwd = !h.scopeCwd
+	h.cwd = cwd
+	h.matchIdx = 0
+	h.runSearch()
+	h.showMatch(buf)
+}
+
+// typeSearch is bound to Key: NotDefined, so it runs after go-prompt has
+// already inserted the typed bytes into buf. It recovers the newly typed
+// text by diffing buf against the last string we rendered into it, then
+// folds that into the query and re-searches.
+func (h *history) typeSearch(buf *prompt.Buffer) {
+	if !h.searching {
+		return
+	}
+
+	typed := strings.TrimPrefix(buf.Text(), h.searchDisplay)
+	h.query += typed
+	h.matchIdx = 0
+	h.runSearch()
+	h.showMatch(buf)
+}
+
+// backspaceSearch is bound to Backspace/Ctrl-H during search mode, trimming
+// one rune off the query and re-searching.
+func (h *history) backspaceSearch(buf *prompt.Buffer) {
+	if !h.searching {
+		buf.DeleteBeforeCursor(1)
+		return
+	}
+
+	if h.query != "" {
+		runes := []rune(h.query)
+		h.query = string(runes[:len(runes)-1])
+	}
+
+	h.matchIdx = 0
+	h.runSearch()
+	h.showMatch(buf)
+}
+
+// cancelSearch is bound to Ctrl-G/Escape, restoring the buffer text as it
+// was before the search began.
+func (h *history) cancelSearch(buf *prompt.Buffer) {
+	if !h.searching {
+		return
+	}
+
+	h.searching = false
+	h.searchDisplay = ""
+
+	clearBuffer(buf)
+	buf.InsertText(h.savedText, false, true)
+}
+
+// showMatch renders the entry at matchIdx (or an empty buffer, if there is
+// no match) into buf, and records what was rendered so the next typeSearch
+// call can recover newly typed characters.
+func (h *history) showMatch(buf *prompt.Buffer) {
+	var text string
+	if h.matchIdx < len(h.matches) {
+		text = h.matches[h.matchIdx].Cmd
+	}
+
+	clearBuffer(buf)
+	buf.InsertText(text, false, true)
+	h.searchDisplay = text
+}
+
+func clearBuffer(buf *prompt.Buffer) {
+	buf.DeleteBeforeCursor(utf8.RuneCountInString(buf.Text()))
+}
+
+// SearchPrompt returns the live prompt prefix for an in-progress reverse
+// incremental search, and false when no search is active.
+func (h *history) SearchPrompt() (string, bool) {
+	if !h.searching {
+		return "", false
+	}
+
+	label := "reverse-i-search"
+	if h.scopeCwd {
+		label = "cwd-" + label
+	}
+	if len(h.matches) == 0 {
+		label = "failed " + label
+	}
+
+	return fmt.Sprintf("(%s)`%s': ", label, h.query), true
 }
 
 func (h *history) nextOlder(text string) (*Entry, bool) {
@@ -166,8 +397,20 @@ func (h *history) Newer(buf *prompt.Buffer) (*prompt.Buffer, bool) {
 	return buf, false
 }
 
-// todo: read the curent contents and reconcile with the new contents (will want to add some type of ordering mechanism)
+// Sync persists every submitted entry. For a JSONL-backed history this
+// reconciles against whatever is already on disk (another concurrent shell
+// may have written entries of its own) rather than blindly appending: disk
+// and in-memory entries are merged by Seq, in-memory wins on a collision,
+// and the union is written back sorted by Seq.
 func (h *history) Sync() error {
+	if h.sqlite != nil {
+		return h.sqlite.upsert(h.entries[:len(h.entries)-1])
+	}
+
+	if h.jsonl != nil {
+		return h.jsonl.reconcile(h.entries[:len(h.entries)-1])
+	}
+
 	data, err := yaml.Marshal(h.entries[:len(h.entries)-1])
 	if err != nil {
 		return fmt.Errorf("could not marshal history entries: %w", err)
@@ -179,3 +422,64 @@ func (h *history) Sync() error {
 
 	return nil
 }
+
+// Reload re-reads entries from the backing SQLite database or JSONL file (if
+// either is in use), replacing h.entries wholesale - as when the history was
+// edited by another wrash instance or by hand. It's a no-op for a plain
+// in-memory history with neither backend set.
+func (h *history) Reload() error {
+	var entries []*Entry
+	var err error
+
+	switch {
+	case h.sqlite != nil:
+		entries, err = h.sqlite.loadEntries()
+	case h.jsonl != nil:
+		entries, err = loadJSONLEntries(h.jsonl.path)
+	default:
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	nextSeq := nextSeqAfter(entries)
+
+	newEntries := make([]*Entry, len(entries), len(entries)+1)
+	copy(newEntries, entries)
+	newEntries = append(newEntries, &Entry{Base: h.base})
+
+	h.entries = newEntries
+	h.current = len(newEntries) - 1
+	h.nextSeq = nextSeq
+
+	return nil
+}
+
+// Close releases any resources held open by the history's backing store
+// (currently, only a SQLite database connection). It's a no-op for a
+// JSONL- or YAML-backed history.
+func (h *history) Close() error {
+	if h.sqlite != nil {
+		return h.sqlite.Close()
+	}
+
+	return nil
+}
+
+// RecordResult attaches the outcome of the most recently submitted entry
+// (its exit code, duration, working directory, and environment hash) so a
+// JSONL-backed history's next Sync captures it alongside the base and cmd
+// recorded by Add.
+func (h *history) RecordResult(exitCode int, duration time.Duration, cwd string, envHash string) {
+	if len(h.entries) < 2 {
+		return
+	}
+
+	entry := h.entries[len(h.entries)-2]
+	entry.Ts = time.Now()
+	entry.Exit = exitCode
+	entry.DurationMs = duration.Milliseconds()
+	entry.Cwd = cwd
+	entry.EnvHash = envHash
+}
@@ -0,0 +1,224 @@
+package wrash
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// jsonlWriter appends Entry records to an append-only JSONL history file at
+// path, guarding it with a mutex so concurrent Syncs (e.g. from goroutines in
+// the same process) can't interleave partial writes.
+type jsonlWriter struct {
+	mu   sync.Mutex
+	path string
+}
+
+func (w *jsonlWriter) append(entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
+	if err != nil {
+		return fmt.Errorf("could not open history file: %w", err)
+	}
+	defer file.Close()
+
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("could not marshal history entry: %w", err)
+		}
+
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("could not append history entry: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reconcile merges entries against whatever is currently on disk at w.path,
+// preferring an in-memory entry over a disk entry sharing the same Seq, then
+// rewrites the file sorted by Seq.
+func (w *jsonlWriter) reconcile(entries []*Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	onDisk, err := loadJSONLEntries(w.path)
+	if err != nil {
+		return err
+	}
+
+	bySeq := make(map[int64]*Entry, len(onDisk)+len(entries))
+	for _, entry := range onDisk {
+		bySeq[entry.Seq] = entry
+	}
+	for _, entry := range entries {
+		bySeq[entry.Seq] = entry
+	}
+
+	merged := make([]*Entry, 0, len(bySeq))
+	for _, entry := range bySeq {
+		merged = append(merged, entry)
+	}
+	sort.Slice(merged, func(i, j int) bool {
+		return merged[i].Seq < merged[j].Seq
+	})
+
+	var buf strings.Builder
+	for _, entry := range merged {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return fmt.Errorf("could not marshal history entry: %w", err)
+		}
+
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	if err := os.WriteFile(w.path, []byte(buf.String()), 0666); err != nil {
+		return fmt.Errorf("could not sync history: %w", err)
+	}
+
+	return nil
+}
+
+// NewJSONLHistory builds a history backed by an append-only JSONL log at
+// path, migrating a legacy YAML history file in its place (if one exists)
+// on first use. Unlike NewHistory, Sync only appends newly-added entries
+// rather than rewriting the whole file.
+func NewJSONLHistory(base string, path string) (*history, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if legacyPath := legacyHistoryPath(path); legacyPath != "" {
+			if _, err := os.Stat(legacyPath); err == nil {
+				if err := migrateYAMLHistory(legacyPath, path); err != nil {
+					return nil, fmt.Errorf("could not migrate legacy history: %w", err)
+				}
+			}
+		}
+	}
+
+	entries, err := loadJSONLEntries(path)
+	if err != nil {
+		return nil, err
+	}
+
+	h := NewHistory(base, io.Discard, entries).(*history)
+	h.jsonl = &jsonlWriter{path: path}
+	h.path = path
+
+	return h, nil
+}
+
+// legacyHistoryPath returns the pre-JSONL YAML history path a JSONL history
+// at path should migrate from, or "" if path doesn't end in ".jsonl".
+func legacyHistoryPath(path string) string {
+	ext := filepath.Ext(path)
+	if ext != ".jsonl" {
+		return ""
+	}
+
+	return strings.TrimSuffix(path, ext) + ".yaml"
+}
+
+func loadJSONLEntries(path string) ([]*Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return []*Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read history file: %w", err)
+	}
+	defer file.Close()
+
+	var entries []*Entry
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var entry Entry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			return nil, fmt.Errorf("could not unmarshal history entry: %w", err)
+		}
+
+		entries = append(entries, &entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read history file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// migrateYAMLHistory converts a legacy YAML history file at yamlPath into
+// JSONL entries appended at jsonlPath, then renames yamlPath to a ".bak"
+// sibling so the migration only ever runs once.
+func migrateYAMLHistory(yamlPath string, jsonlPath string) error {
+	data, err := os.ReadFile(yamlPath)
+	if err != nil {
+		return fmt.Errorf("could not read legacy history file: %w", err)
+	}
+
+	var entries []*Entry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("could not unmarshal legacy history entries: %w", err)
+	}
+
+	// The legacy YAML format never wrote Seq, so every entry unmarshals with
+	// the zero value. reconcile (and upsert, for SQLite) key purely by Seq,
+	// so leaving them all at 0 would collapse every migrated entry onto a
+	// single slot the next time the history syncs.
+	var nextSeq int64
+	for _, entry := range entries {
+		entry.Seq = nextSeq
+		nextSeq++
+	}
+
+	writer := &jsonlWriter{path: jsonlPath}
+	if err := writer.append(entries); err != nil {
+		return err
+	}
+
+	if err := os.Rename(yamlPath, yamlPath+".bak"); err != nil {
+		return fmt.Errorf("could not back up legacy history file: %w", err)
+	}
+
+	return nil
+}
+
+// envHash returns a stable content hash of an environment map, recorded with
+// each JSONL history entry so `!!history` queries can later be correlated
+// against the environment a command ran under.
+func envHash(env map[string]string) string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, env[k])
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,87 @@
+package wrash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonlWriterReconcile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	w := &jsonlWriter{path: path}
+
+	require.NoError(t, w.reconcile([]*Entry{
+		{Base: "foo", Cmd: "a", Seq: 0},
+		{Base: "foo", Cmd: "b", Seq: 1},
+	}))
+
+	entries, err := loadJSONLEntries(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].Cmd)
+	assert.Equal(t, "b", entries[1].Cmd)
+
+	// a concurrent writer appends a seq 2 entry directly to the file
+	require.NoError(t, (&jsonlWriter{path: path}).append([]*Entry{
+		{Base: "foo", Cmd: "c", Seq: 2},
+	}))
+
+	// reconciling our in-memory seq 1 entry (edited) should merge rather
+	// than clobber the concurrently-written seq 2 entry
+	require.NoError(t, w.reconcile([]*Entry{
+		{Base: "foo", Cmd: "a", Seq: 0},
+		{Base: "foo", Cmd: "b-edited", Seq: 1},
+	}))
+
+	entries, err = loadJSONLEntries(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"a", "b-edited", "c"}, []string{entries[0].Cmd, entries[1].Cmd, entries[2].Cmd})
+}
+
+// TestNewJSONLHistoryMigratesYAMLWithUniqueSeq guards against a regression
+// where migrated legacy entries (which never had a Seq in the old YAML
+// format) all carried the zero value, so the very next Sync's reconcile -
+// which merges purely by Seq - collapsed every migrated entry onto a single
+// slot.
+func TestNewJSONLHistoryMigratesYAMLWithUniqueSeq(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "history.yaml")
+	jsonlPath := filepath.Join(dir, "history.jsonl")
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("- base: foo\n  cmd: a\n- base: foo\n  cmd: b\n- base: foo\n  cmd: c\n"), 0666))
+
+	h, err := NewJSONLHistory("foo", jsonlPath)
+	require.NoError(t, err)
+	require.NoError(t, h.Sync())
+
+	entries, err := loadJSONLEntries(jsonlPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{entries[0].Cmd, entries[1].Cmd, entries[2].Cmd})
+	assert.Equal(t, []int64{0, 1, 2}, []int64{entries[0].Seq, entries[1].Seq, entries[2].Seq})
+}
+
+func TestNewJSONLHistorySeedsNextSeq(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+
+	h, err := NewJSONLHistory("foo", path)
+	require.NoError(t, err)
+
+	h.Add("a")
+	require.NoError(t, h.Sync())
+
+	h2, err := NewJSONLHistory("foo", path)
+	require.NoError(t, err)
+	h2.Add("b")
+	require.NoError(t, h2.Sync())
+
+	entries, err := loadJSONLEntries(path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, int64(0), entries[0].Seq)
+	assert.Equal(t, int64(1), entries[1].Seq)
+}
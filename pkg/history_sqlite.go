@@ -0,0 +1,224 @@
+package wrash
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v3"
+)
+
+// sqliteSchema creates the entries table a sqliteStore reads and writes, and
+// the index doHistory's --cwd/--since/--failed filters rely on to stay
+// snappy against a large history.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	seq         INTEGER PRIMARY KEY,
+	base        TEXT NOT NULL,
+	cmd         TEXT NOT NULL,
+	ts          INTEGER NOT NULL,
+	exit        INTEGER NOT NULL,
+	duration_ms INTEGER NOT NULL,
+	cwd         TEXT NOT NULL,
+	env_hash    TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_entries_base_cwd_ts ON entries (base, cwd, ts);
+`
+
+// sqliteStore persists history entries to a SQLite database at path. Unlike
+// jsonlWriter's read-merge-rewrite reconcile, entries are merged through a
+// SQL upsert keyed on seq, so concurrent shells merge through SQLite's own
+// locking instead of wrash reconciling files by hand.
+type sqliteStore struct {
+	db   *sql.DB
+	path string
+}
+
+func newSqliteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open history database: %w", err)
+	}
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("could not initialize history schema: %w", err)
+	}
+
+	return &sqliteStore{db: db, path: path}, nil
+}
+
+func (s *sqliteStore) loadEntries() ([]*Entry, error) {
+	rows, err := s.db.Query(`SELECT seq, base, cmd, ts, exit, duration_ms, cwd, env_hash FROM entries ORDER BY seq`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query history entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []*Entry
+	for rows.Next() {
+		var entry Entry
+		var ts int64
+
+		if err := rows.Scan(&entry.Seq, &entry.Base, &entry.Cmd, &ts, &entry.Exit, &entry.DurationMs, &entry.Cwd, &entry.EnvHash); err != nil {
+			return nil, fmt.Errorf("could not scan history entry: %w", err)
+		}
+
+		if ts != 0 {
+			entry.Ts = time.Unix(ts, 0)
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// upsert writes entries to the database, replacing any existing row sharing
+// a seq - the same "in-memory wins on collision" merge semantics
+// jsonlWriter.reconcile gives the JSONL backend, but expressed as a single
+// SQL statement per entry instead of a full-file rewrite.
+func (s *sqliteStore) upsert(entries []*Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("could not begin history transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO entries (seq, base, cmd, ts, exit, duration_ms, cwd, env_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(seq) DO UPDATE SET
+			base = excluded.base,
+			cmd = excluded.cmd,
+			ts = excluded.ts,
+			exit = excluded.exit,
+			duration_ms = excluded.duration_ms,
+			cwd = excluded.cwd,
+			env_hash = excluded.env_hash
+	`)
+	if err != nil {
+		return fmt.Errorf("could not prepare history upsert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, entry := range entries {
+		var ts int64
+		if !entry.Ts.IsZero() {
+			ts = entry.Ts.Unix()
+		}
+
+		if _, err := stmt.Exec(entry.Seq, entry.Base, entry.Cmd, ts, entry.Exit, entry.DurationMs, entry.Cwd, entry.EnvHash); err != nil {
+			return fmt.Errorf("could not write history entry: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// NewSQLiteHistory builds a history backed by a SQLite database at path,
+// migrating a legacy JSONL or YAML history file in its place (if one
+// exists) on first use. Sync upserts newly-added and newly-completed
+// entries rather than rewriting the whole store.
+func NewSQLiteHistory(base string, path string) (*history, error) {
+	firstUse := !fileExists(path)
+
+	store, err := newSqliteStore(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if firstUse {
+		legacy, err := migrateLegacyHistory(path)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("could not migrate legacy history: %w", err)
+		}
+
+		if err := store.upsert(legacy); err != nil {
+			store.Close()
+			return nil, fmt.Errorf("could not migrate legacy history: %w", err)
+		}
+	}
+
+	entries, err := store.loadEntries()
+	if err != nil {
+		store.Close()
+		return nil, err
+	}
+
+	h := NewHistory(base, io.Discard, entries).(*history)
+	h.sqlite = store
+	h.path = path
+
+	return h, nil
+}
+
+// migrateLegacyHistory looks for a JSONL or (failing that) YAML history file
+// sharing dbPath's base name, returning its entries (or nil if neither
+// exists). Whichever legacy file is found is renamed to a ".bak" sibling, so
+// the migration only ever runs once.
+func migrateLegacyHistory(dbPath string) ([]*Entry, error) {
+	base := strings.TrimSuffix(dbPath, filepath.Ext(dbPath))
+
+	if jsonlPath := base + ".jsonl"; fileExists(jsonlPath) {
+		entries, err := loadJSONLEntries(jsonlPath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.Rename(jsonlPath, jsonlPath+".bak"); err != nil {
+			return nil, fmt.Errorf("could not back up legacy history file: %w", err)
+		}
+
+		return entries, nil
+	}
+
+	if yamlPath := base + ".yaml"; fileExists(yamlPath) {
+		data, err := os.ReadFile(yamlPath)
+		if err != nil {
+			return nil, fmt.Errorf("could not read legacy history file: %w", err)
+		}
+
+		var entries []*Entry
+		if err := yaml.Unmarshal(data, &entries); err != nil {
+			return nil, fmt.Errorf("could not unmarshal legacy history entries: %w", err)
+		}
+
+		// The legacy YAML format never wrote seq, so every entry unmarshals
+		// with the zero value. upsert's ON CONFLICT(seq) keys purely on seq,
+		// so leaving them all at 0 would collapse every migrated entry onto a
+		// single row the next time the history syncs.
+		var nextSeq int64
+		for _, entry := range entries {
+			entry.Seq = nextSeq
+			nextSeq++
+		}
+
+		if err := os.Rename(yamlPath, yamlPath+".bak"); err != nil {
+			return nil, fmt.Errorf("could not back up legacy history file: %w", err)
+		}
+
+		return entries, nil
+	}
+
+	return nil, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,136 @@
+package wrash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteStoreUpsert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := newSqliteStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.upsert([]*Entry{
+		{Base: "foo", Cmd: "a", Seq: 0},
+		{Base: "foo", Cmd: "b", Seq: 1},
+	}))
+
+	entries, err := store.loadEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "a", entries[0].Cmd)
+	assert.Equal(t, "b", entries[1].Cmd)
+
+	// re-upserting an existing seq replaces it rather than duplicating it
+	require.NoError(t, store.upsert([]*Entry{
+		{Base: "foo", Cmd: "b-edited", Seq: 1, Exit: 1},
+	}))
+
+	entries, err = store.loadEntries()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "b-edited", entries[1].Cmd)
+	assert.Equal(t, 1, entries[1].Exit)
+}
+
+func TestNewSQLiteHistorySeedsNextSeq(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	h, err := NewSQLiteHistory("foo", path)
+	require.NoError(t, err)
+
+	h.Add("a")
+	require.NoError(t, h.Sync())
+	require.NoError(t, h.Close())
+
+	h2, err := NewSQLiteHistory("foo", path)
+	require.NoError(t, err)
+	h2.Add("b")
+	require.NoError(t, h2.Sync())
+	require.NoError(t, h2.Close())
+
+	entries, err := newSqliteStoreEntries(t, path)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, []int64{0, 1}, []int64{entries[0].Seq, entries[1].Seq})
+}
+
+func TestNewSQLiteHistoryMigratesJSONL(t *testing.T) {
+	dir := t.TempDir()
+	jsonlPath := filepath.Join(dir, "history.jsonl")
+	dbPath := filepath.Join(dir, "history.db")
+
+	require.NoError(t, (&jsonlWriter{path: jsonlPath}).append([]*Entry{
+		{Base: "foo", Cmd: "a", Seq: 0},
+	}))
+
+	h, err := NewSQLiteHistory("foo", dbPath)
+	require.NoError(t, err)
+	defer h.Close()
+
+	require.Len(t, h.entries, 2) // migrated entry, plus the in-progress one NewHistory appends
+	assert.Equal(t, "a", h.entries[0].Cmd)
+
+	_, err = os.Stat(jsonlPath + ".bak")
+	assert.NoError(t, err, "legacy jsonl file should have been backed up")
+}
+
+// TestNewSQLiteHistoryMigratesYAMLWithUniqueSeq guards against a regression
+// where migrated legacy entries (which never had a seq in the old YAML
+// format) all carried the zero value, so the very next Sync's upsert - whose
+// ON CONFLICT(seq) keys purely on seq - overwrote every migrated entry but
+// one.
+func TestNewSQLiteHistoryMigratesYAMLWithUniqueSeq(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "history.yaml")
+	dbPath := filepath.Join(dir, "history.db")
+
+	require.NoError(t, os.WriteFile(yamlPath, []byte("- base: foo\n  cmd: a\n- base: foo\n  cmd: b\n- base: foo\n  cmd: c\n"), 0666))
+
+	h, err := NewSQLiteHistory("foo", dbPath)
+	require.NoError(t, err)
+	defer h.Close()
+
+	require.NoError(t, h.Sync())
+
+	entries, err := newSqliteStoreEntries(t, dbPath)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{entries[0].Cmd, entries[1].Cmd, entries[2].Cmd})
+	assert.Equal(t, []int64{0, 1, 2}, []int64{entries[0].Seq, entries[1].Seq, entries[2].Seq})
+}
+
+func TestSQLiteHistoryReloadPicksUpExternalWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+
+	h, err := NewSQLiteHistory("foo", path)
+	require.NoError(t, err)
+	defer h.Close()
+
+	other, err := newSqliteStore(path)
+	require.NoError(t, err)
+	defer other.Close()
+	require.NoError(t, other.upsert([]*Entry{
+		{Base: "foo", Cmd: "from-another-shell", Seq: 0},
+	}))
+
+	require.NoError(t, h.Reload())
+	require.Len(t, h.entries, 2) // the reloaded entry, plus the in-progress one
+	assert.Equal(t, "from-another-shell", h.entries[0].Cmd)
+	assert.Equal(t, int64(1), h.nextSeq)
+}
+
+func newSqliteStoreEntries(t *testing.T, path string) ([]*Entry, error) {
+	t.Helper()
+
+	store, err := newSqliteStore(path)
+	require.NoError(t, err)
+	defer store.Close()
+
+	return store.loadEntries()
+}
@@ -54,6 +54,7 @@ func TestHistoryAdd(t *testing.T) {
 			},
 			{
 				Cmd: "!!help",
+				Seq: 1,
 			},
 			{
 				Base: base,
@@ -290,3 +291,95 @@ func TestHistoryFullTraverse(t *testing.T) {
 	assert.True(t, found)
 	assert.Equal(t, "abc", newer.Text())
 }
+
+func TestHistorySearch(t *testing.T) {
+	h := NewHistory("foo", io.Discard, []*Entry{
+		{Base: "foo", Cmd: "git status"},
+		{Base: "bar", Cmd: "git log"},
+		{Base: "foo", Cmd: "!!help"},
+		{Base: "foo", Cmd: "ls"},
+	}).(*history)
+
+	matches := h.Search("git", "foo")
+	require.Len(t, matches, 1)
+	assert.Equal(t, "git status", matches[0].Cmd)
+
+	matches = h.Search("", "foo")
+	require.Len(t, matches, 3)
+	assert.Equal(t, []string{"ls", "!!help", "git status"}, []string{matches[0].Cmd, matches[1].Cmd, matches[2].Cmd})
+}
+
+func TestHistorySearchCwd(t *testing.T) {
+	h := NewHistory("foo", io.Discard, []*Entry{
+		{Base: "foo", Cmd: "git status", Cwd: "/a"},
+		{Base: "foo", Cmd: "git log", Cwd: "/b"},
+	}).(*history)
+
+	matches := h.SearchCwd("git", "foo", "/a")
+	require.Len(t, matches, 1)
+	assert.Equal(t, "git status", matches[0].Cmd)
+}
+
+func TestHistoryInteractiveSearch(t *testing.T) {
+	h := NewHistory("foo", io.Discard, []*Entry{
+		{Base: "foo", Cmd: "git status"},
+		{Base: "foo", Cmd: "git log"},
+	}).(*history)
+
+	buf := prompt.NewBuffer()
+	buf.InsertText("git", false, true)
+
+	h.startSearch(buf)
+	assert.Equal(t, "git log", buf.Text())
+	prefix, ok := h.SearchPrompt()
+	assert.True(t, ok)
+	assert.Equal(t, "(reverse-i-search)`git': ", prefix)
+
+	h.startSearch(buf)
+	assert.Equal(t, "git status", buf.Text())
+
+	buf.InsertText(" status", false, true)
+	h.typeSearch(buf)
+	assert.Equal(t, "git status", buf.Text())
+
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	h.backspaceSearch(buf)
+	assert.Equal(t, "git log", buf.Text())
+
+	h.cancelSearch(buf)
+	assert.Equal(t, "git", buf.Text())
+	_, ok = h.SearchPrompt()
+	assert.False(t, ok)
+}
+
+func TestHistoryToggleScope(t *testing.T) {
+	h := NewHistory("foo", io.Discard, []*Entry{
+		{Base: "foo", Cmd: "git status", Cwd: "/a"},
+		{Base: "foo", Cmd: "git log", Cwd: "/b"},
+	}).(*history)
+
+	buf := prompt.NewBuffer()
+
+	// toggling outside of a search is a no-op
+	h.toggleScope(buf, "/a")
+	assert.False(t, h.scopeCwd)
+
+	h.startSearch(buf)
+	assert.Equal(t, "git log", buf.Text())
+
+	h.toggleScope(buf, "/a")
+	assert.True(t, h.scopeCwd)
+	assert.Equal(t, "git status", buf.Text())
+	prefix, ok := h.SearchPrompt()
+	assert.True(t, ok)
+	assert.Equal(t, "(cwd-reverse-i-search)`': ", prefix)
+
+	h.toggleScope(buf, "/a")
+	assert.False(t, h.scopeCwd)
+	assert.Equal(t, "git log", buf.Text())
+}
@@ -0,0 +1,57 @@
+package hub
+
+import "fmt"
+
+type ErrNotFound struct {
+	Name string
+}
+
+func (e ErrNotFound) Error() string {
+	return fmt.Sprintf("no hub entry named '%s'", e.Name)
+}
+
+type ErrNotInstalled struct {
+	Name string
+}
+
+func (e ErrNotInstalled) Error() string {
+	return fmt.Sprintf("'%s' is not installed", e.Name)
+}
+
+type ErrNoIndex struct {
+	Offline bool
+}
+
+func (e ErrNoIndex) Error() string {
+	if e.Offline {
+		return "no cached hub index and offline mode is enabled; run '!!hub update' while online first"
+	}
+
+	return "no cached hub index; run '!!hub update' first"
+}
+
+type ErrOffline struct {
+	Op string
+}
+
+func (e ErrOffline) Error() string {
+	return fmt.Sprintf("cannot %s: hub is in offline mode", e.Op)
+}
+
+type ErrInvalidName struct {
+	Name string
+}
+
+func (e ErrInvalidName) Error() string {
+	return fmt.Sprintf("invalid hub entry name '%s'", e.Name)
+}
+
+type ErrChecksumMismatch struct {
+	Name     string
+	Expected string
+	Actual   string
+}
+
+func (e ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch for '%s': expected %s but got %s", e.Name, e.Expected, e.Actual)
+}
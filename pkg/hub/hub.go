@@ -0,0 +1,379 @@
+// Package hub fetches, caches, and installs YAML completion suggestions from
+// one or more remote indexes, in the spirit of a package manager for wrash
+// completions.
+package hub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/samber/lo"
+	"gopkg.in/yaml.v3"
+)
+
+// validEntryName matches the characters allowed in an index entry's Name.
+// Entry.Name is joined directly into a filesystem path by completionPath, so
+// this also guards against path traversal from a malicious or compromised
+// index (e.g. a Name of "../../../../home/user/.bashrc").
+var validEntryName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// Entry describes a single installable completion file listed in a remote
+// index.
+type Entry struct {
+	Name        string   `yaml:"name"`
+	Version     string   `yaml:"version"`
+	Sha256      string   `yaml:"sha256"`
+	Url         string   `yaml:"url"`
+	Description string   `yaml:"description"`
+	Tags        []string `yaml:"tags"`
+}
+
+// Index is the remote YAML document listing the entries a Hub can install.
+type Index struct {
+	Entries []Entry `yaml:"entries"`
+}
+
+// Installed records the version and checksum of a hub entry that was
+// installed locally, so Upgrade can tell whether it is out of date.
+type Installed struct {
+	Name    string `yaml:"name"`
+	Version string `yaml:"version"`
+	Sha256  string `yaml:"sha256"`
+}
+
+type manifest struct {
+	Installed []Installed `yaml:"installed"`
+}
+
+// Hub fetches, caches, and installs completion YAML files from a remote
+// index.
+type Hub struct {
+	// IndexUrl is the URL of the remote index YAML document.
+	IndexUrl string
+
+	// CacheDir holds the cached index and install manifest (defaults to
+	// "<base>/hub").
+	CacheDir string
+
+	// CompletionDir is where installed completion YAML files are written
+	// (defaults to "<base>/completions"), matching where LoadSuggestions
+	// looks for a base command's completions.
+	CompletionDir string
+
+	// Offline disables all network access; Update fails immediately and
+	// other operations only ever consult the cache.
+	Offline bool
+
+	Client *http.Client
+}
+
+// New returns a Hub rooted at baseDir (typically "~/.wrash") pointing at
+// indexUrl.
+func New(indexUrl string, baseDir string) *Hub {
+	return &Hub{
+		IndexUrl:      indexUrl,
+		CacheDir:      filepath.Join(baseDir, "hub"),
+		CompletionDir: filepath.Join(baseDir, "completions"),
+		Client:        http.DefaultClient,
+	}
+}
+
+func (h *Hub) indexPath() string {
+	return filepath.Join(h.CacheDir, ".index.yaml")
+}
+
+func (h *Hub) manifestPath() string {
+	return filepath.Join(h.CacheDir, "installed.yaml")
+}
+
+func (h *Hub) completionPath(name string) (string, error) {
+	if !validEntryName.MatchString(name) || strings.Contains(name, "..") {
+		return "", ErrInvalidName{Name: name}
+	}
+
+	return filepath.Join(h.CompletionDir, name+".yaml"), nil
+}
+
+func (h *Hub) get(url string) ([]byte, error) {
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch '%s': %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch '%s': unexpected status %s", url, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("could not read response body: %w", err)
+	}
+
+	return body, nil
+}
+
+// Update refreshes the cached index from IndexUrl.
+func (h *Hub) Update() error {
+	if h.Offline {
+		return ErrOffline{Op: "update the hub index"}
+	}
+
+	body, err := h.get(h.IndexUrl)
+	if err != nil {
+		return err
+	}
+
+	if err := yaml.Unmarshal(body, &Index{}); err != nil {
+		return fmt.Errorf("could not parse hub index: %w", err)
+	}
+
+	if err := os.MkdirAll(h.CacheDir, 0o755); err != nil {
+		return fmt.Errorf("could not create hub cache dir: %w", err)
+	}
+
+	if err := os.WriteFile(h.indexPath(), body, 0o644); err != nil {
+		return fmt.Errorf("could not write hub index: %w", err)
+	}
+
+	return nil
+}
+
+func (h *Hub) loadIndex() (*Index, error) {
+	data, err := os.ReadFile(h.indexPath())
+	if os.IsNotExist(err) {
+		return nil, ErrNoIndex{Offline: h.Offline}
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read hub index: %w", err)
+	}
+
+	index := &Index{}
+	if err := yaml.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("could not parse hub index: %w", err)
+	}
+
+	return index, nil
+}
+
+func (h *Hub) loadManifest() (*manifest, error) {
+	data, err := os.ReadFile(h.manifestPath())
+	if os.IsNotExist(err) {
+		return &manifest{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read hub manifest: %w", err)
+	}
+
+	m := &manifest{}
+	if err := yaml.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("could not parse hub manifest: %w", err)
+	}
+
+	return m, nil
+}
+
+func (h *Hub) saveManifest(m *manifest) error {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("could not marshal hub manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(h.CacheDir, 0o755); err != nil {
+		return fmt.Errorf("could not create hub cache dir: %w", err)
+	}
+
+	return os.WriteFile(h.manifestPath(), data, 0o644)
+}
+
+// Search returns the entries in the cached index whose name or tags match
+// pattern.
+func (h *Hub) Search(pattern string) ([]Entry, error) {
+	index, err := h.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("could not compile pattern: %w", err)
+	}
+
+	return lo.Filter(index.Entries, func(entry Entry, _ int) bool {
+		if re.MatchString(entry.Name) {
+			return true
+		}
+
+		return lo.SomeBy(entry.Tags, func(tag string) bool {
+			return re.MatchString(tag)
+		})
+	}), nil
+}
+
+func (h *Hub) findEntry(name string) (*Entry, error) {
+	index, err := h.loadIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, found := lo.Find(index.Entries, func(entry Entry) bool {
+		return entry.Name == name
+	})
+	if !found {
+		return nil, ErrNotFound{Name: name}
+	}
+
+	return &entry, nil
+}
+
+// Install downloads the named entry, verifies its checksum, and writes it
+// into CompletionDir.
+func (h *Hub) Install(name string) error {
+	if h.Offline {
+		return ErrOffline{Op: "install '" + name + "'"}
+	}
+
+	path, err := h.completionPath(name)
+	if err != nil {
+		return err
+	}
+
+	entry, err := h.findEntry(name)
+	if err != nil {
+		return err
+	}
+
+	body, err := h.get(entry.Url)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(body)
+	actual := hex.EncodeToString(sum[:])
+	if entry.Sha256 != "" && actual != entry.Sha256 {
+		return ErrChecksumMismatch{Name: name, Expected: entry.Sha256, Actual: actual}
+	}
+
+	if err := os.MkdirAll(h.CompletionDir, 0o755); err != nil {
+		return fmt.Errorf("could not create completion dir: %w", err)
+	}
+
+	if err := os.WriteFile(path, body, 0o644); err != nil {
+		return fmt.Errorf("could not write completion file: %w", err)
+	}
+
+	m, err := h.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	m.Installed = lo.Filter(m.Installed, func(i Installed, _ int) bool {
+		return i.Name != name
+	})
+	m.Installed = append(m.Installed, Installed{
+		Name:    name,
+		Version: entry.Version,
+		Sha256:  actual,
+	})
+
+	return h.saveManifest(m)
+}
+
+// List returns the entries installed locally.
+func (h *Hub) List() ([]Installed, error) {
+	m, err := h.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.Installed, nil
+}
+
+// Remove deletes an installed completion file and its manifest entry.
+func (h *Hub) Remove(name string) error {
+	m, err := h.loadManifest()
+	if err != nil {
+		return err
+	}
+
+	if _, found := lo.Find(m.Installed, func(i Installed) bool { return i.Name == name }); !found {
+		return ErrNotInstalled{Name: name}
+	}
+
+	path, err := h.completionPath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("could not remove completion file: %w", err)
+	}
+
+	m.Installed = lo.Filter(m.Installed, func(i Installed, _ int) bool {
+		return i.Name != name
+	})
+
+	return h.saveManifest(m)
+}
+
+// Upgrade reinstalls name if the index's checksum differs from the
+// installed one. It is a no-op if the entry is already up to date.
+func (h *Hub) Upgrade(name string) (upgraded bool, err error) {
+	m, err := h.loadManifest()
+	if err != nil {
+		return false, err
+	}
+
+	installed, found := lo.Find(m.Installed, func(i Installed) bool { return i.Name == name })
+	if !found {
+		return false, ErrNotInstalled{Name: name}
+	}
+
+	entry, err := h.findEntry(name)
+	if err != nil {
+		return false, err
+	}
+
+	if entry.Sha256 == installed.Sha256 {
+		return false, nil
+	}
+
+	if err := h.Install(name); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// UpgradeAll upgrades every installed entry whose index checksum differs
+// from the installed one, returning the names that were upgraded.
+func (h *Hub) UpgradeAll() ([]string, error) {
+	m, err := h.loadManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var upgraded []string
+	for _, installed := range m.Installed {
+		did, err := h.Upgrade(installed.Name)
+		if err != nil {
+			return upgraded, err
+		}
+
+		if did {
+			upgraded = append(upgraded, installed.Name)
+		}
+	}
+
+	return upgraded, nil
+}
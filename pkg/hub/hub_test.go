@@ -0,0 +1,201 @@
+package hub
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const kubectlPayload = "description: kubectl completions\n"
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestHub(t *testing.T) (*Hub, *httptest.Server) {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		index := Index{
+			Entries: []Entry{
+				{
+					Name:        "kubectl",
+					Version:     "1.0.0",
+					Sha256:      sha256Hex(kubectlPayload),
+					Description: "kubectl completions",
+					Tags:        []string{"kubernetes"},
+				},
+			},
+		}
+
+		data, err := yaml.Marshal(index)
+		require.NoError(t, err)
+		w.Write(data)
+	})
+	mux.HandleFunc("/kubectl.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(kubectlPayload))
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	h := New(server.URL+"/index.yaml", t.TempDir())
+	h.Client = server.Client()
+
+	return h, server
+}
+
+func TestHubUpdateAndSearch(t *testing.T) {
+	h, _ := newTestHub(t)
+
+	require.Error(t, func() error {
+		_, err := h.Search(".*")
+		return err
+	}())
+
+	require.NoError(t, h.Update())
+
+	entries, err := h.Search("kube")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "kubectl", entries[0].Name)
+
+	entries, err = h.Search("nomatch")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestHubInstallListRemoveUpgrade(t *testing.T) {
+	h, server := newTestHub(t)
+	require.NoError(t, h.Update())
+
+	// patch the index's kubectl entry to point at this server's payload
+	indexData, err := os.ReadFile(filepath.Join(h.CacheDir, ".index.yaml"))
+	require.NoError(t, err)
+
+	var index Index
+	require.NoError(t, yaml.Unmarshal(indexData, &index))
+	index.Entries[0].Url = server.URL + "/kubectl.yaml"
+	data, err := yaml.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(h.CacheDir, ".index.yaml"), data, 0o644))
+
+	t.Run("InstallUnknown", func(t *testing.T) {
+		err := h.Install("nope")
+		assert.ErrorIs(t, err, ErrNotFound{Name: "nope"})
+	})
+
+	require.NoError(t, h.Install("kubectl"))
+
+	installedData, err := os.ReadFile(filepath.Join(h.CompletionDir, "kubectl.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, kubectlPayload, string(installedData))
+
+	installed, err := h.List()
+	require.NoError(t, err)
+	require.Len(t, installed, 1)
+	assert.Equal(t, "kubectl", installed[0].Name)
+	assert.Equal(t, "1.0.0", installed[0].Version)
+
+	t.Run("UpgradeUpToDate", func(t *testing.T) {
+		did, err := h.Upgrade("kubectl")
+		require.NoError(t, err)
+		assert.False(t, did)
+	})
+
+	require.NoError(t, h.Remove("kubectl"))
+	_, err = os.Stat(filepath.Join(h.CompletionDir, "kubectl.yaml"))
+	assert.True(t, os.IsNotExist(err))
+
+	assert.ErrorIs(t, h.Remove("kubectl"), ErrNotInstalled{Name: "kubectl"})
+}
+
+func TestHubOffline(t *testing.T) {
+	h, _ := newTestHub(t)
+	h.Offline = true
+
+	err := h.Update()
+	assert.ErrorIs(t, err, ErrOffline{Op: "update the hub index"})
+
+	err = h.Install("kubectl")
+	assert.ErrorIs(t, err, ErrOffline{Op: "install 'kubectl'"})
+}
+
+// TestHubInstallRejectsPathTraversal guards against a malicious or
+// compromised index publishing an entry whose Name escapes CompletionDir via
+// the filesystem path it's joined into.
+func TestHubInstallRejectsPathTraversal(t *testing.T) {
+	mux := http.NewServeMux()
+	const evilName = "../../etc/evil"
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		index := Index{Entries: []Entry{{Name: evilName, Sha256: sha256Hex("pwned\n"), Url: "/evil.yaml"}}}
+		data, _ := yaml.Marshal(index)
+		w.Write(data)
+	})
+	mux.HandleFunc("/evil.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("pwned\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	h := New(server.URL+"/index.yaml", t.TempDir())
+	h.Client = server.Client()
+	require.NoError(t, h.Update())
+
+	indexData, err := os.ReadFile(filepath.Join(h.CacheDir, ".index.yaml"))
+	require.NoError(t, err)
+	var index Index
+	require.NoError(t, yaml.Unmarshal(indexData, &index))
+	index.Entries[0].Url = server.URL + "/evil.yaml"
+	data, err := yaml.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(h.CacheDir, ".index.yaml"), data, 0o644))
+
+	err = h.Install(evilName)
+	assert.ErrorIs(t, err, ErrInvalidName{Name: evilName})
+
+	_, err = os.Stat(filepath.Join(filepath.Dir(h.CompletionDir), "etc", "evil.yaml"))
+	assert.True(t, os.IsNotExist(err), "Install must not write outside CompletionDir")
+}
+
+func TestHubChecksumMismatch(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/index.yaml", func(w http.ResponseWriter, r *http.Request) {
+		index := Index{Entries: []Entry{{Name: "bad", Sha256: "deadbeef"}}}
+		data, _ := yaml.Marshal(index)
+		w.Write(data)
+	})
+	mux.HandleFunc("/bad.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("description: bad\n"))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	h := New(server.URL+"/index.yaml", t.TempDir())
+	h.Client = server.Client()
+	require.NoError(t, h.Update())
+
+	indexData, err := os.ReadFile(filepath.Join(h.CacheDir, ".index.yaml"))
+	require.NoError(t, err)
+	var index Index
+	require.NoError(t, yaml.Unmarshal(indexData, &index))
+	index.Entries[0].Url = server.URL + "/bad.yaml"
+	data, err := yaml.Marshal(index)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(h.CacheDir, ".index.yaml"), data, 0o644))
+
+	err = h.Install("bad")
+	var mismatch ErrChecksumMismatch
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "bad", mismatch.Name)
+}
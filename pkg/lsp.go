@@ -0,0 +1,465 @@
+package wrash
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	prompt "github.com/joshmeranda/go-prompt"
+	"github.com/samber/lo"
+)
+
+// LspConfig describes how to launch and talk to an LSP server for a wrapped
+// command's completions. It is embedded in a CommandSuggestion's `lsp:` block.
+type LspConfig struct {
+	// Command is the LSP server binary to launch, Args its arguments.
+	Command []string `yaml:"command"`
+	Args    []string `yaml:"args"`
+
+	// RootUri is sent as the `rootUri` of the initialize request, if set.
+	RootUri string `yaml:"rootUri"`
+
+	// TriggerCharacters are appended to the characters that will cause a
+	// completion request to be sent as the user types, beyond whatever the
+	// server advertises in its initialize response.
+	TriggerCharacters []string `yaml:"triggerCharacters"`
+
+	// TimeoutMs bounds how long we wait for a completion response before
+	// falling back to the YAML-based suggestions. Defaults to 200ms.
+	TimeoutMs int `yaml:"timeoutMs"`
+}
+
+func (c *LspConfig) timeout() time.Duration {
+	if c == nil || c.TimeoutMs <= 0 {
+		return 200 * time.Millisecond
+	}
+
+	return time.Duration(c.TimeoutMs) * time.Millisecond
+}
+
+// rpcRequest and rpcResponse implement the JSON-RPC 2.0 envelope used by the
+// Language Server Protocol.
+type rpcRequest struct {
+	JsonRpc string      `json:"jsonrpc"`
+	Id      int         `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JsonRpc string          `json:"jsonrpc"`
+	Id      int             `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("lsp error %d: %s", e.Code, e.Message)
+}
+
+// CompletionItem mirrors the subset of LSP's textDocument/completion result
+// wrash understands.
+type CompletionItem struct {
+	Label         string `json:"label"`
+	Detail        string `json:"detail"`
+	Documentation string `json:"documentation"`
+	InsertText    string `json:"insertText"`
+}
+
+// writeFramedMessage writes v to w using the LSP `Content-Length` framing.
+func writeFramedMessage(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("could not marshal message: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return fmt.Errorf("could not write header: %w", err)
+	}
+
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("could not write body: %w", err)
+	}
+
+	return nil
+}
+
+// readFramedMessage reads a single `Content-Length`-framed message from r.
+func readFramedMessage(r *bufio.Reader) ([]byte, error) {
+	contentLength := -1
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("could not read header: %w", err)
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+
+		if name, value, found := strings.Cut(line, ":"); found && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			contentLength, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+
+	if contentLength < 0 {
+		return nil, fmt.Errorf("message is missing Content-Length header")
+	}
+
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("could not read body: %w", err)
+	}
+
+	return body, nil
+}
+
+// LSPClient is a long-lived JSON-RPC 2.0 connection to a single LSP server
+// child process, speaking the `Content-Length`-framed protocol over its
+// stdin/stdout.
+type LSPClient struct {
+	w io.WriteCloser
+	r *bufio.Reader
+	c io.Closer
+
+	nextId  int32
+	pending sync.Map // map[int]chan *rpcResponse
+
+	uri     string
+	version int
+	mu      sync.Mutex
+}
+
+// NewLSPClient wraps an already-connected transport (the stdin/stdout pipes
+// of an LSP server child process, or an in-memory pipe in tests) in an
+// LSPClient and starts reading responses in the background.
+func NewLSPClient(w io.WriteCloser, r io.Reader, c io.Closer) *LSPClient {
+	client := &LSPClient{
+		w:   w,
+		r:   bufio.NewReader(r),
+		c:   c,
+		uri: "wrash://virtual/prompt",
+	}
+
+	go client.readLoop()
+
+	return client
+}
+
+// StartLSPClient launches command as a child process and returns an
+// LSPClient connected to its stdio.
+func StartLSPClient(command []string) (*LSPClient, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("no lsp command configured")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not open lsp stdin: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("could not open lsp stdout: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start lsp command: %w", err)
+	}
+
+	return NewLSPClient(stdin, stdout, processCloser{cmd}), nil
+}
+
+type processCloser struct {
+	cmd *exec.Cmd
+}
+
+func (p processCloser) Close() error {
+	if p.cmd.Process == nil {
+		return nil
+	}
+
+	return p.cmd.Process.Kill()
+}
+
+func (c *LSPClient) readLoop() {
+	for {
+		body, err := readFramedMessage(c.r)
+		if err != nil {
+			return
+		}
+
+		var resp rpcResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			continue
+		}
+
+		if ch, found := c.pending.LoadAndDelete(resp.Id); found {
+			ch.(chan *rpcResponse) <- &resp
+		}
+	}
+}
+
+func (c *LSPClient) call(method string, params interface{}, result interface{}) error {
+	id := int(atomic.AddInt32(&c.nextId, 1))
+
+	ch := make(chan *rpcResponse, 1)
+	c.pending.Store(id, ch)
+	defer c.pending.Delete(id)
+
+	c.mu.Lock()
+	err := writeFramedMessage(c.w, rpcRequest{
+		JsonRpc: "2.0",
+		Id:      id,
+		Method:  method,
+		Params:  params,
+	})
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	resp := <-ch
+	if resp.Error != nil {
+		return resp.Error
+	}
+
+	if result == nil || len(resp.Result) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(resp.Result, result)
+}
+
+func (c *LSPClient) notify(method string, params interface{}) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return writeFramedMessage(c.w, rpcRequest{
+		JsonRpc: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// Initialize performs the initialize/initialized handshake. rootUri may be
+// empty.
+func (c *LSPClient) Initialize(rootUri string) error {
+	params := map[string]interface{}{
+		"processId":    nil,
+		"rootUri":      nilIfEmpty(rootUri),
+		"capabilities": map[string]interface{}{},
+	}
+
+	if err := c.call("initialize", params, nil); err != nil {
+		return fmt.Errorf("could not initialize lsp server: %w", err)
+	}
+
+	return c.notify("initialized", map[string]interface{}{})
+}
+
+func nilIfEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
+
+// DidOpen sends a textDocument/didOpen notification for the virtual document
+// backing the current prompt line.
+func (c *LSPClient) DidOpen(text string) error {
+	c.version = 1
+
+	return c.notify("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":        c.uri,
+			"languageId": "wrash",
+			"version":    c.version,
+			"text":       text,
+		},
+	})
+}
+
+// DidChange sends a textDocument/didChange notification replacing the whole
+// contents of the virtual document.
+func (c *LSPClient) DidChange(text string) error {
+	c.version++
+
+	return c.notify("textDocument/didChange", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":     c.uri,
+			"version": c.version,
+		},
+		"contentChanges": []map[string]interface{}{
+			{"text": text},
+		},
+	})
+}
+
+// Completion requests textDocument/completion at the given zero-based
+// line/character and returns the server's completion items.
+func (c *LSPClient) Completion(line, character int) ([]CompletionItem, error) {
+	params := map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri": c.uri,
+		},
+		"position": map[string]interface{}{
+			"line":      line,
+			"character": character,
+		},
+	}
+
+	var result struct {
+		Items []CompletionItem `json:"items"`
+	}
+
+	if err := c.call("textDocument/completion", params, &result); err != nil {
+		var items []CompletionItem
+		if err := c.call("textDocument/completion", params, &items); err == nil {
+			return items, nil
+		}
+
+		return nil, err
+	}
+
+	return result.Items, nil
+}
+
+// Shutdown performs the shutdown/exit sequence and closes the underlying
+// transport.
+func (c *LSPClient) Shutdown() error {
+	_ = c.call("shutdown", nil, nil)
+	_ = c.notify("exit", nil)
+
+	if c.c != nil {
+		return c.c.Close()
+	}
+
+	return nil
+}
+
+// LSPSuggestion is a Suggestor backed by a long-lived LSP server. If the
+// server is unavailable, slow to respond, or not configured, it falls back
+// to the YAML-based fallback Suggestor.
+type LSPSuggestion struct {
+	client   *LSPClient
+	fallback Suggestor
+	timeout  time.Duration
+}
+
+// NewLSPSuggestion starts the LSP server described by cfg (if any) and
+// returns a Suggestor that prefers its completions, falling back to
+// fallback when the server is unavailable or times out. cfg may be nil, in
+// which case fallback is always used.
+func NewLSPSuggestion(cfg *LspConfig, fallback Suggestor) (Suggestor, error) {
+	if cfg == nil || len(cfg.Command) == 0 {
+		return fallback, nil
+	}
+
+	client, err := StartLSPClient(append(append([]string{}, cfg.Command...), cfg.Args...))
+	if err != nil {
+		return fallback, fmt.Errorf("could not start lsp client: %w", err)
+	}
+
+	return newLSPSuggestion(client, cfg.RootUri, cfg.timeout(), fallback)
+}
+
+// newLSPSuggestion runs the initialize/didOpen handshake against an
+// already-connected client and wraps it in an LSPSuggestion, falling back to
+// fallback if either step fails. Split out from NewLSPSuggestion so tests can
+// drive the handshake against an in-memory client instead of a subprocess.
+func newLSPSuggestion(client *LSPClient, rootUri string, timeout time.Duration, fallback Suggestor) (Suggestor, error) {
+	if err := client.Initialize(rootUri); err != nil {
+		_ = client.Shutdown()
+		return fallback, fmt.Errorf("could not initialize lsp client: %w", err)
+	}
+
+	// The server must see a textDocument/didOpen for the virtual document
+	// before any textDocument/didChange; Suggest only ever sends didChange.
+	if err := client.DidOpen(""); err != nil {
+		_ = client.Shutdown()
+		return fallback, fmt.Errorf("could not open lsp virtual document: %w", err)
+	}
+
+	return &LSPSuggestion{
+		client:   client,
+		fallback: fallback,
+		timeout:  timeout,
+	}, nil
+}
+
+func (s *LSPSuggestion) Suggest(args []string, completeLast bool) []prompt.Suggest {
+	text := strings.Join(args, " ")
+
+	line, character := 0, len(text)
+
+	if err := s.client.DidChange(text); err != nil {
+		return s.fallback.Suggest(args, completeLast)
+	}
+
+	type result struct {
+		items []CompletionItem
+		err   error
+	}
+
+	done := make(chan result, 1)
+	go func() {
+		items, err := s.client.Completion(line, character)
+		done <- result{items, err}
+	}()
+
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return s.fallback.Suggest(args, completeLast)
+		}
+
+		return lo.Map(res.items, func(item CompletionItem, _ int) prompt.Suggest {
+			text := item.InsertText
+			if text == "" {
+				text = item.Label
+			}
+
+			description := item.Detail
+			if description == "" {
+				description = item.Documentation
+			}
+
+			return prompt.Suggest{
+				Text:        text,
+				Description: description,
+			}
+		})
+	case <-time.After(s.timeout):
+		return s.fallback.Suggest(args, completeLast)
+	}
+}
+
+// Close shuts down the underlying LSP server, if one was started.
+func (s *LSPSuggestion) Close() error {
+	if s.client == nil {
+		return nil
+	}
+
+	return s.client.Shutdown()
+}
@@ -0,0 +1,201 @@
+package wrash
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordedMethods collects RPC method names across goroutines, for tests
+// asserting the order notifications were sent in.
+type recordedMethods struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+func (r *recordedMethods) add(method string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods = append(r.methods, method)
+}
+
+func (r *recordedMethods) snapshot() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.methods...)
+}
+
+// pipeCloser adapts an io.Closer pair so NewLSPClient has something to close.
+type pipeCloser struct {
+	a, b io.Closer
+}
+
+func (p pipeCloser) Close() error {
+	_ = p.a.Close()
+	return p.b.Close()
+}
+
+// fakeLSPServer serves a single initialize request and then echoes a fixed
+// completion item for every textDocument/completion request it receives. If
+// methods is non-nil, every request and notification's method name is
+// appended to it (guarded by a mutex, since the caller reads it from another
+// goroutine).
+func fakeLSPServer(t *testing.T, serverR io.Reader, serverW io.WriteCloser, methods *recordedMethods) {
+	t.Helper()
+
+	reader := bufio.NewReader(serverR)
+
+	for {
+		body, err := readFramedMessage(reader)
+		if err != nil {
+			return
+		}
+
+		var req rpcRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+
+		if methods != nil {
+			methods.add(req.Method)
+		}
+
+		if req.Method == "exit" {
+			return
+		}
+
+		if req.Id == 0 {
+			// notification, nothing to reply to
+			continue
+		}
+
+		var result interface{}
+		switch req.Method {
+		case "initialize":
+			result = map[string]interface{}{}
+		case "shutdown":
+			result = nil
+		case "textDocument/completion":
+			result = map[string]interface{}{
+				"items": []CompletionItem{
+					{Label: "get", Detail: "list resources", InsertText: "get"},
+				},
+			}
+		}
+
+		_ = writeFramedMessage(serverW, rpcResponse{
+			JsonRpc: "2.0",
+			Id:      req.Id,
+			Result:  mustMarshal(t, result),
+		})
+	}
+}
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	t.Helper()
+
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+
+	return b
+}
+
+func newFakeLSPClient(t *testing.T) *LSPClient {
+	t.Helper()
+
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	go fakeLSPServer(t, serverR, serverW, nil)
+
+	return NewLSPClient(clientW, clientR, pipeCloser{clientW, serverW})
+}
+
+func TestLSPClientInitializeAndComplete(t *testing.T) {
+	client := newFakeLSPClient(t)
+
+	require.NoError(t, client.Initialize(""))
+	require.NoError(t, client.DidOpen("kubectl "))
+
+	items, err := client.Completion(0, 8)
+	require.NoError(t, err)
+	assert.Equal(t, []CompletionItem{{Label: "get", Detail: "list resources", InsertText: "get"}}, items)
+
+	require.NoError(t, client.Shutdown())
+}
+
+func TestLSPSuggestionFallback(t *testing.T) {
+	fallback := &CommandSuggestion{
+		Args: Arg{Choices: []string{"fallback-choice"}},
+	}
+
+	t.Run("NoLspConfigured", func(t *testing.T) {
+		suggestor, err := NewLSPSuggestion(nil, fallback)
+		require.NoError(t, err)
+		assert.Same(t, Suggestor(fallback), suggestor)
+	})
+
+	t.Run("UnreachableServer", func(t *testing.T) {
+		suggestor, err := NewLSPSuggestion(&LspConfig{Command: []string{"wrash-does-not-exist-binary"}}, fallback)
+		require.Error(t, err)
+		assert.Same(t, Suggestor(fallback), suggestor)
+	})
+}
+
+// TestNewLSPSuggestionSendsDidOpenBeforeDidChange guards against the
+// production path only ever sending textDocument/didChange: a server can't
+// validly receive a didChange for a document it was never told was opened.
+func TestNewLSPSuggestionSendsDidOpenBeforeDidChange(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+
+	var methods recordedMethods
+	go fakeLSPServer(t, serverR, serverW, &methods)
+
+	client := NewLSPClient(clientW, clientR, pipeCloser{clientW, serverW})
+
+	suggestor, err := newLSPSuggestion(client, "", time.Second, &CommandSuggestion{})
+	require.NoError(t, err)
+
+	suggestor.Suggest([]string{"kubectl", "get"}, true)
+
+	// Shutdown's "exit" notification is fire-and-forget, so don't depend on
+	// the fake server having processed it by the time Close returns - only
+	// the handshake and completion order (which must be deterministic) are
+	// asserted here.
+	require.NoError(t, suggestor.(*LSPSuggestion).Close())
+
+	assert.Equal(t, []string{"initialize", "initialized", "textDocument/didOpen", "textDocument/didChange", "textDocument/completion"}, methods.snapshot()[:5])
+}
+
+func TestLSPSuggestionUsesFallbackOnTimeout(t *testing.T) {
+	clientR, serverW := io.Pipe()
+	serverR, clientW := io.Pipe()
+	defer serverW.Close()
+	defer clientW.Close()
+
+	// server that never responds to completion requests
+	go func() {
+		reader := bufio.NewReader(serverR)
+		for {
+			if _, err := readFramedMessage(reader); err != nil {
+				return
+			}
+		}
+	}()
+
+	client := NewLSPClient(clientW, clientR, pipeCloser{clientW, serverW})
+
+	suggestion := &LSPSuggestion{
+		client:   client,
+		fallback: &CommandSuggestion{Args: Arg{Choices: []string{"fallback-choice"}}},
+		timeout:  10 * time.Millisecond,
+	}
+
+	suggestions := suggestion.Suggest([]string{"foo"}, true)
+	assert.Equal(t, suggestion.fallback.Suggest([]string{"foo"}, true), suggestions)
+}
@@ -0,0 +1,123 @@
+package wrash
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"testing/fstest"
+)
+
+// MemFS is an in-memory FS backed by a testing/fstest.MapFS, letting tests
+// exercise glob expansion, completion, and `cd` against a virtual tree
+// instead of fixtures on disk. Paths are fs.FS-style (slash-separated,
+// relative to the virtual root), with Chdir/Getwd tracking a current
+// directory the same way OsFS tracks the process's.
+type MemFS struct {
+	fstest.MapFS
+
+	cwd  string
+	home string
+}
+
+// NewMemFS builds a MemFS rooted at files, with both the current directory
+// and home directory starting at the virtual root ("/").
+func NewMemFS(files fstest.MapFS) *MemFS {
+	return &MemFS{
+		MapFS: files,
+		cwd:   ".",
+		home:  ".",
+	}
+}
+
+// SetHome sets the directory UserHomeDir reports, as a path relative to the
+// virtual root (e.g. "home/user").
+func (f *MemFS) SetHome(dir string) {
+	f.home = cleanRel(dir)
+}
+
+// resolve turns name - absolute (leading "/") or relative to f.cwd - into a
+// path rooted at the virtual filesystem, suitable for passing to the
+// embedded fs.FS.
+func (f *MemFS) resolve(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return cleanRel(name)
+	}
+
+	return path.Join(f.cwd, name)
+}
+
+func cleanRel(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	if name == "" {
+		return "."
+	}
+
+	return path.Clean(name)
+}
+
+// Glob resolves pattern against the current directory (unless it's
+// absolute), then returns matches in the same relative-or-absolute form
+// pattern was expressed in, matching filepath.Glob's behavior.
+func (f *MemFS) Glob(pattern string) ([]string, error) {
+	absolute := strings.HasPrefix(pattern, "/")
+
+	matches, err := fs.Glob(f.MapFS, f.resolve(pattern))
+	if err != nil {
+		return nil, err
+	}
+
+	if absolute {
+		for i, m := range matches {
+			matches[i] = "/" + m
+		}
+		return matches, nil
+	}
+
+	prefix := f.cwd + "/"
+	if f.cwd == "." {
+		prefix = ""
+	}
+
+	for i, m := range matches {
+		matches[i] = strings.TrimPrefix(m, prefix)
+	}
+
+	return matches, nil
+}
+
+func (f *MemFS) Stat(name string) (fs.FileInfo, error) {
+	return f.MapFS.Stat(f.resolve(name))
+}
+
+func (f *MemFS) Chdir(dir string) error {
+	resolved := f.resolve(dir)
+
+	info, err := f.MapFS.Stat(resolved)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", dir)
+	}
+
+	f.cwd = resolved
+	return nil
+}
+
+func (f *MemFS) Getwd() (string, error) {
+	if f.cwd == "." {
+		return "/", nil
+	}
+
+	return "/" + f.cwd, nil
+}
+
+func (f *MemFS) UserHomeDir() (string, error) {
+	if f.home == "." {
+		return "/", nil
+	}
+
+	return "/" + f.home, nil
+}
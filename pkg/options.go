@@ -4,6 +4,8 @@ import (
 	"io"
 
 	prompt "github.com/joshmeranda/go-prompt"
+	"github.com/joshmeranda/wrash/pkg/hub"
+	"github.com/joshmeranda/wrash/pkg/termstatus"
 )
 
 type Option func(*Session) error
@@ -56,3 +58,68 @@ func OptionEnvironment(env map[string]string) Option {
 		return nil
 	}
 }
+
+func OptionHub(h *hub.Hub) Option {
+	return func(s *Session) error {
+		s.hub = h
+		return nil
+	}
+}
+
+// OptionStatusProducer supplies the StatusProducer used to render the live
+// status region below the prompt while a wrapped command runs.
+func OptionStatusProducer(p termstatus.StatusProducer) Option {
+	return func(s *Session) error {
+		s.statusProducer = p
+		return nil
+	}
+}
+
+// OptionStatusEnabled sets the initial enabled state of the live status
+// region (toggled at runtime via the `!!status` builtin).
+func OptionStatusEnabled(enabled bool) Option {
+	return func(s *Session) error {
+		s.statusEnabled = enabled
+		return nil
+	}
+}
+
+// OptionConfigPath overrides the config file wrash loads its per-base
+// completers and aliases from, which otherwise defaults to
+// DefaultConfigPath().
+func OptionConfigPath(path string) Option {
+	return func(s *Session) error {
+		s.configPath = path
+		return nil
+	}
+}
+
+// OptionWatchCompletions overrides whether Session watches configPath and
+// the history file's backing store for external changes and hot-reloads
+// them, which otherwise defaults to on while interactive.
+func OptionWatchCompletions(watch bool) Option {
+	return func(s *Session) error {
+		s.watchCompletionsOverride = &watch
+		return nil
+	}
+}
+
+// OptionCompleterRegistry supplies the CompleterRegistry used to resolve a
+// base command's completer and aliases, in place of the empty registry
+// NewSession otherwise constructs.
+func OptionCompleterRegistry(r *CompleterRegistry) Option {
+	return func(s *Session) error {
+		s.completers = r
+		return nil
+	}
+}
+
+// OptionFS supplies the FS glob expansion, `cd`, and file completion
+// consult, in place of the OsFS NewSession otherwise constructs. Embedders
+// can use this to sandbox a Session to a virtual or chroot-like subtree.
+func OptionFS(fsys FS) Option {
+	return func(s *Session) error {
+		s.fs = fsys
+		return nil
+	}
+}
@@ -1,19 +1,32 @@
 package wrash
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 	"unicode"
 
+	"github.com/fsnotify/fsnotify"
 	prompt "github.com/joshmeranda/go-prompt"
 	"github.com/joshmeranda/wrash/pkg/args"
+	"github.com/joshmeranda/wrash/pkg/hub"
+	"github.com/joshmeranda/wrash/pkg/termstatus"
 	"github.com/samber/lo"
 	"github.com/urfave/cli/v2"
 )
 
+// EnvHubIndexUrl names the environment variable used to configure the
+// default hub's index URL when no OptionHub is supplied.
+const EnvHubIndexUrl = "WRASH_HUB_INDEX_URL"
+
 const (
 	runeset = "`~!@#$%^&*()-=+[{]}\\|;:'\",.<>/?_"
 )
@@ -71,10 +84,31 @@ type Session struct {
 	environ map[string]string
 
 	history          *history
+	hub              *hub.Hub
 	exitCalled       bool
 	previousExitCode int
 	apps             map[string]*cli.App
 	isFrozen         bool
+
+	statusProducer termstatus.StatusProducer
+	statusEnabled  bool
+
+	// completers holds the per-base completers and aliases loaded from
+	// configPath (if set), plus any registered programmatically via
+	// CompleterRegistry.Register.
+	completers  *CompleterRegistry
+	configPath  string
+	fileWatcher *fsnotify.Watcher
+
+	// watchCompletionsOverride, if non-nil, overrides whether NewSession
+	// starts watchFiles; nil defers to interactive. Set via
+	// OptionWatchCompletions.
+	watchCompletionsOverride *bool
+
+	// fs is the filesystem glob expansion, `cd`, and file completion
+	// consult, in place of the real filesystem, so embedders can sandbox a
+	// Session to a virtual or chroot-like subtree.
+	fs FS
 }
 
 func NewSession(base string, opts ...Option) (*Session, error) {
@@ -88,6 +122,8 @@ func NewSession(base string, opts ...Option) (*Session, error) {
 		stdout: os.Stdout,
 		stderr: os.Stderr,
 		stdin:  os.Stdin,
+
+		statusEnabled: true,
 	}
 
 	for _, opt := range opts {
@@ -100,6 +136,40 @@ func NewSession(base string, opts ...Option) (*Session, error) {
 		session.history = NewHistory(base, io.Discard, make([]*Entry, 0)).(*history)
 	}
 
+	if session.hub == nil {
+		home, _ := os.UserHomeDir()
+		session.hub = hub.New(os.Getenv(EnvHubIndexUrl), filepath.Join(home, ".wrash"))
+	}
+
+	if session.statusProducer == nil {
+		session.statusProducer = termstatus.NewElapsedProducer()
+	}
+
+	if session.fs == nil {
+		session.fs = OsFS{}
+	}
+
+	if session.completers == nil {
+		session.completers = NewCompleterRegistry(session.fs)
+	}
+
+	if session.configPath == "" {
+		if p, err := DefaultConfigPath(); err == nil {
+			session.configPath = p
+		}
+	}
+
+	session.loadHubCompletion()
+	session.loadConfig()
+
+	watchCompletions := session.interactive
+	if session.watchCompletionsOverride != nil {
+		watchCompletions = *session.watchCompletionsOverride
+	}
+	if watchCompletions {
+		session.watchFiles()
+	}
+
 	session.initBuiltins()
 
 	if session.interactive {
@@ -120,6 +190,34 @@ func NewSession(base string, opts ...Option) (*Session, error) {
 					Key: prompt.ControlLeft,
 					Fn:  goPreviousBoundary,
 				},
+				prompt.KeyBind{
+					Key: prompt.ControlR,
+					Fn:  session.history.startSearch,
+				},
+				prompt.KeyBind{
+					Key: prompt.ControlT,
+					Fn:  session.toggleSearchScope,
+				},
+				prompt.KeyBind{
+					Key: prompt.ControlG,
+					Fn:  session.history.cancelSearch,
+				},
+				prompt.KeyBind{
+					Key: prompt.Escape,
+					Fn:  session.history.cancelSearch,
+				},
+				prompt.KeyBind{
+					Key: prompt.Backspace,
+					Fn:  session.history.backspaceSearch,
+				},
+				prompt.KeyBind{
+					Key: prompt.ControlH,
+					Fn:  session.history.backspaceSearch,
+				},
+				prompt.KeyBind{
+					Key: prompt.NotDefined,
+					Fn:  session.history.typeSearch,
+				},
 			),
 		)
 	}
@@ -134,56 +232,345 @@ func (s *Session) executor(str string) {
 		return
 	}
 
-	cmd, err := args.Parse(s.Base + " " + str)
+	start := time.Now()
+	defer func() {
+		cwd, _ := s.fs.Getwd()
+		s.history.RecordResult(s.previousExitCode, time.Since(start), cwd, envHash(s.environ))
+	}()
+
+	script, err := args.ParseScript(str)
 	if err != nil {
 		fmt.Fprintf(s.stderr, "could not parse args: %s\n", err)
 		return
 	}
 
-	expanded, err := cmd.Expand(func(key string) string {
+	if err := s.runScript(script); err != nil {
+		fmt.Fprintf(s.stderr, "could not run command: %s\n", err)
+	}
+}
+
+// runScript runs each Statement in script in order, returning the last run
+// Statement's error. A Statement joined to the next by `&&` only lets the
+// next run if it exits zero; `||` only lets the next run if it exits
+// non-zero; `;` always lets the next run.
+func (s *Session) runScript(script args.Script) error {
+	var err error
+	run := true
+
+	for _, stmt := range script {
+		if !run {
+			break
+		}
+
+		s.previousExitCode = 0
+		err = s.runPipeline(stmt.Pipeline)
+
+		switch stmt.Op {
+		case args.OpAnd:
+			run = s.previousExitCode == 0
+		case args.OpOr:
+			run = s.previousExitCode != 0
+		default:
+			run = true
+		}
+	}
+
+	return err
+}
+
+// syncWriter serializes concurrent writes to a shared io.Writer, e.g. an
+// os.Stdout that more than one pipeline stage writes to at once.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// stageIO holds the resolved reader/writer/error-writer for a single
+// pipeline stage, after accounting for its position in the pipeline and any
+// redirections attached to it.
+type stageIO struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	closers []io.Closer
+}
+
+func (sio *stageIO) close() {
+	for _, c := range sio.closers {
+		c.Close()
+	}
+}
+
+// Execute runs command as a nested Script for command substitution
+// (`$(...)`/backticks), capturing its stdout instead of writing to the
+// session's own. It shares s's Base, environ, and fs, but writes to a
+// buffer instead of the terminal and never touches history or the live
+// status region, so a substitution can't recurse into either.
+func (s *Session) Execute(command string) (string, error) {
+	script, err := args.ParseScript(command)
+	if err != nil {
+		return "", err
+	}
+
+	sub := *s
+	var out bytes.Buffer
+	sub.stdout = &out
+	sub.statusEnabled = false
+	sub.statusProducer = nil
+
+	err = sub.runScript(script)
+	return out.String(), err
+}
+
+// openRedirections resolves stage's redirection targets against env and
+// layers them over defaults, opening any files they name. The caller must
+// call the returned stageIO's close() once the stage has finished running.
+func (s *Session) openRedirections(stage args.Stage, defaults stageIO, env func(string) string) (stageIO, error) {
+	sio := defaults
+
+	for _, redirection := range stage.Redirections {
+		target, err := redirection.ExpandTarget(env, s.fs, s)
+		if err != nil {
+			return sio, fmt.Errorf("could not expand redirection target: %w", err)
+		}
+
+		switch redirection.Kind {
+		case args.RedirectIn:
+			f, err := os.Open(target)
+			if err != nil {
+				return sio, fmt.Errorf("could not open %q for reading: %w", target, err)
+			}
+			sio.closers = append(sio.closers, f)
+			sio.stdin = f
+		case args.RedirectOut, args.RedirectAppend, args.RedirectOutAndErr:
+			flags := os.O_WRONLY | os.O_CREATE
+			if redirection.Kind == args.RedirectAppend {
+				flags |= os.O_APPEND
+			} else {
+				flags |= os.O_TRUNC
+			}
+
+			f, err := os.OpenFile(target, flags, 0644)
+			if err != nil {
+				return sio, fmt.Errorf("could not open %q for writing: %w", target, err)
+			}
+			sio.closers = append(sio.closers, f)
+			sio.stdout = f
+			if redirection.Kind == args.RedirectOutAndErr {
+				sio.stderr = f
+			}
+		case args.RedirectErr:
+			f, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				return sio, fmt.Errorf("could not open %q for writing: %w", target, err)
+			}
+			sio.closers = append(sio.closers, f)
+			sio.stderr = f
+		}
+	}
+
+	return sio, nil
+}
+
+// runPipeline runs every stage of pipeline concurrently, connecting adjacent
+// stages with an io.Pipe unless a stage's own redirections override that
+// stream. It returns the last stage's error, matching how the shell reports
+// the exit status of a pipeline.
+func (s *Session) runPipeline(pipeline args.Pipeline) error {
+	env := func(key string) string {
 		return s.environ[key]
-	})
+	}
+
+	// Every stage defaults to s.stderr, and (for the last stage) s.stdout, so
+	// those two streams are shared across however many stages run
+	// concurrently. Serialize writes to them so two stages can't interleave
+	// or corrupt each other's output.
+	sharedStdout := &syncWriter{w: s.stdout}
+	sharedStderr := &syncWriter{w: s.stderr}
+
+	stageIOs := make([]stageIO, len(pipeline))
+	for i := range pipeline {
+		stageIOs[i].stdin = s.stdin
+		stageIOs[i].stdout = sharedStdout
+		stageIOs[i].stderr = sharedStderr
+	}
+
+	for i := 0; i < len(pipeline)-1; i++ {
+		r, w := io.Pipe()
+		stageIOs[i].stdout = w
+		stageIOs[i+1].stdin = r
+	}
+
+	for i, stage := range pipeline {
+		resolved, err := s.openRedirections(stage, stageIOs[i], env)
+		if err != nil {
+			return err
+		}
+		stageIOs[i] = resolved
+	}
+
+	errs := make([]error, len(pipeline))
+
+	var wg sync.WaitGroup
+	for i, stage := range pipeline {
+		wg.Add(1)
+		go func(i int, stage args.Stage) {
+			defer wg.Done()
+			defer stageIOs[i].close()
+
+			if pw, ok := stageIOs[i].stdout.(*io.PipeWriter); ok {
+				defer pw.Close()
+			}
+
+			toTerminal := stageIOs[i].stdout == io.Writer(sharedStdout)
+			errs[i] = s.runStage(stage, stageIOs[i], i == 0, toTerminal, env)
+		}(i, stage)
+	}
+
+	wg.Wait()
+
+	last := errs[len(errs)-1]
+	if last != nil {
+		switch err := last.(type) {
+		case *exec.ExitError:
+			s.previousExitCode = err.ExitCode()
+		default:
+			s.previousExitCode = 127
+		}
+	}
+
+	return last
+}
+
+// runStage expands and runs a single Stage, dispatching to a builtin app or
+// an external command as appropriate. prependBase controls whether an
+// external command's argv is prefixed with s.Base, which only applies to the
+// first stage of a pipeline. toTerminal reports whether sio.stdout is
+// ultimately the session's own stdout (as opposed to a pipe or redirection
+// target), which gates the live status region.
+func (s *Session) runStage(stage args.Stage, sio stageIO, prependBase bool, toTerminal bool, env func(string) string) error {
+	expanded, err := stage.Command.Expand(env, s.fs, s)
 	if err != nil {
-		fmt.Fprintf(s.stderr, "could not expand args: %s\n", err)
-		return
+		return fmt.Errorf("could not expand args: %w", err)
 	}
 
-	s.previousExitCode = 0
+	if len(expanded) == 0 {
+		return nil
+	}
 
-	if isBuiltin(str) {
-		expanded = expanded[1:]
+	if isBuiltin(expanded[0]) {
 		app, found := s.apps[expanded[0][2:]]
 		if !found {
-			fmt.Fprintf(s.stderr, "unknown command: %s\n", expanded[0])
-			s.previousExitCode = 127
-			return
+			return fmt.Errorf("unknown command: %s", expanded[0])
 		}
 
-		if err := app.Run(expanded); err != nil {
-			fmt.Fprintf(s.stderr, "could not run command: %s\n", err)
-			s.previousExitCode = 127
+		app.Reader = sio.stdin
+		app.Writer = sio.stdout
+		app.ErrWriter = sio.stderr
+
+		err := app.Run(expanded)
+
+		// No builtin reads app.Reader today. If this stage's stdin is the
+		// read end of a previous stage's io.Pipe, that stage's Write blocks
+		// until something reads it - drain whatever's left so a pipeline
+		// feeding a builtin doesn't hang forever waiting for a reader that
+		// will never come.
+		if pr, ok := sio.stdin.(*io.PipeReader); ok {
+			io.Copy(io.Discard, pr)
 		}
-	} else {
-		cmd := exec.Command(expanded[0], expanded[1:]...)
-		cmd.Stdout = s.stdout
-		cmd.Stderr = s.stderr
-		cmd.Stdin = s.stdin
-
-		if err := cmd.Run(); err != nil {
-			switch err := err.(type) {
-			case *exec.ExitError:
-				s.previousExitCode = err.ExitCode()
-			default:
-				s.previousExitCode = 127
-				fmt.Fprintf(s.stderr, "could not run command: %s\n", err)
-			}
+
+		return err
+	}
+
+	if prependBase {
+		if alias, found := s.completers.Alias(s.Base, expanded[0]); found {
+			expanded[0] = alias
 		}
+		expanded = append([]string{s.Base}, expanded...)
+	}
+
+	cmd := exec.Command(expanded[0], expanded[1:]...)
+	cmd.Stdin = sio.stdin
+
+	if prependBase && toTerminal && s.interactive && s.statusEnabled && s.statusProducer != nil {
+		return s.runWithStatus(cmd)
 	}
+
+	cmd.Stdout = sio.stdout
+	cmd.Stderr = sio.stderr
+	return cmd.Run()
+}
+
+// runWithStatus runs cmd with its combined stdout/stderr scrolling above a
+// live termstatus.Status block driven by s.statusProducer, resetting the
+// producer first so state like elapsed time is measured from this command
+// rather than carried over from the last one.
+func (s *Session) runWithStatus(cmd *exec.Cmd) error {
+	s.statusProducer.Reset()
+
+	outR, outW := io.Pipe()
+	cmd.Stdout = outW
+	cmd.Stderr = outW
+
+	status := termstatus.New(s.stdout, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go status.Run(ctx)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		scanner := bufio.NewScanner(outR)
+		for scanner.Scan() {
+			line := scanner.Text()
+			status.Print(line)
+			s.statusProducer.Feed(line)
+			status.SetLines(s.statusProducer.Lines())
+		}
+	}()
+
+	ticker := time.NewTicker(200 * time.Millisecond)
+	tickerDone := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				status.SetLines(s.statusProducer.Lines())
+			case <-tickerDone:
+				return
+			}
+		}
+	}()
+
+	err := cmd.Run()
+
+	outW.Close()
+	wg.Wait()
+	close(tickerDone)
+
+	return err
 }
 
 func (s *Session) livePrefix() (string, bool) {
+	if prefix, ok := s.history.SearchPrompt(); ok {
+		return prefix, true
+	}
+
 	user := os.Getenv("USER")
-	wd, _ := os.Getwd()
+	wd, _ := s.fs.Getwd()
 	return fmt.Sprintf("[%s %s] %s > ", user, wd, s.Base), true
 }
 
@@ -201,18 +588,174 @@ func (s *Session) completer(doc prompt.Document) []prompt.Suggest {
 			return strings.HasPrefix(s.Text, doc.TextBeforeCursor())
 		})
 	default:
-		suggestions = fileCompleter(doc)
+		suggestions = s.completers.Completer(s.Base)(doc)
 	}
 
 	return suggestions
 }
 
+// loadHubCompletion registers a completer for s.Base from
+// "<hub.CompletionDir>/<base>.yaml" if one was installed by "!!hub install",
+// so the hub's completions take effect the next time a session for that
+// base starts without requiring a config.yaml entry. A config.yaml completer
+// for the same base, loaded afterward by loadConfig, still takes precedence.
+func (s *Session) loadHubCompletion() {
+	if s.hub == nil {
+		return
+	}
+
+	path := filepath.Join(s.hub.CompletionDir, s.Base+".yaml")
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return
+	}
+
+	suggestor, err := LoadSuggestions(path)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "warning: could not load hub completion %q: %s\n", path, err)
+		return
+	}
+
+	s.completers.Register(s.Base, suggestorCompleter(suggestor))
+}
+
+// reloadHubCompletion re-reads "<hub.CompletionDir>/<base>.yaml" after it
+// changed on disk (installed, upgraded, or removed via "!!hub"), in the same
+// order loadHubCompletion/loadConfig run at startup so a config.yaml
+// completer for the same base still takes precedence over the hub's.
+func (s *Session) reloadHubCompletion() {
+	s.loadHubCompletion()
+	s.loadConfig()
+}
+
+// loadConfig (re)loads s.configPath into s.completers. A missing file is not
+// an error (wrash runs fine with no config); a parse error is reported to
+// s.stderr and the previously-loaded config (if any) is kept as-is.
+func (s *Session) loadConfig() {
+	if s.configPath == "" {
+		return
+	}
+
+	if _, err := os.Stat(s.configPath); os.IsNotExist(err) {
+		return
+	}
+
+	cfg, err := LoadConfig(s.configPath)
+	if err != nil {
+		fmt.Fprintf(s.stderr, "warning: could not load config %q, keeping previous config: %s\n", s.configPath, err)
+		return
+	}
+
+	s.completers.loadConfig(cfg)
+}
+
+// reloadDebounce is how long watchFiles waits after the last matching event
+// for a given path before reloading it, so a burst of events from a single
+// editor save (e.g. a truncate followed by a write) triggers one reload
+// rather than several.
+const reloadDebounce = 200 * time.Millisecond
+
+// reloadHistory re-reads the history file from disk, as when it was edited
+// by another wrash instance or by hand.
+func (s *Session) reloadHistory() {
+	if err := s.history.Reload(); err != nil {
+		fmt.Fprintf(s.stderr, "warning: could not reload history: %s\n", err)
+	}
+}
+
+// watchFiles starts a background watch on s.configPath's, the history's
+// backing file's, and the hub's per-Base completion file's directories
+// (rather than the files themselves, since editors commonly replace a file
+// via rename-on-save or truncate-then-write rather than writing it in place;
+// watching the directory means a remove event never drops coverage of the
+// path, since we were never watching the path itself) and reloads whichever
+// one changed. Bursts of events for the same path within reloadDebounce
+// collapse into a single reload.
+func (s *Session) watchFiles() {
+	targets := make(map[string]func())
+	if s.configPath != "" {
+		targets[filepath.Clean(s.configPath)] = s.loadConfig
+	}
+	if historyPath := s.history.path; historyPath != "" {
+		targets[filepath.Clean(historyPath)] = s.reloadHistory
+	}
+	if s.hub != nil {
+		hubCompletionPath := filepath.Join(s.hub.CompletionDir, s.Base+".yaml")
+		targets[filepath.Clean(hubCompletionPath)] = s.reloadHubCompletion
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(s.stderr, "warning: could not watch config file: %s\n", err)
+		return
+	}
+
+	dirs := make(map[string]bool)
+	for target := range targets {
+		dirs[filepath.Dir(target)] = true
+	}
+	for dir := range dirs {
+		// A target's directory may not exist yet (e.g. no config.yaml has
+		// ever been written, or nothing has been installed from the hub);
+		// skip it rather than aborting every other target's watch too.
+		if err := watcher.Add(dir); err != nil {
+			continue
+		}
+	}
+
+	s.fileWatcher = watcher
+
+	timers := make(map[string]*time.Timer)
+
+	go func() {
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				name := filepath.Clean(event.Name)
+				reload, found := targets[name]
+				if !found || event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				if timer, ok := timers[name]; ok {
+					timer.Stop()
+				}
+				timers[name] = time.AfterFunc(reloadDebounce, reload)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				fmt.Fprintf(s.stderr, "warning: error watching config file: %s\n", err)
+			}
+		}
+	}()
+}
+
 func (s *Session) Run() {
 	defer func() {
 		if err := s.history.Sync(); err != nil {
 			fmt.Fprintf(s.stderr, "could not sync history: %s\n", err)
 		}
+
+		if err := s.history.Close(); err != nil {
+			fmt.Fprintf(s.stderr, "could not close history: %s\n", err)
+		}
 	}()
 
 	s.prompt.Run()
 }
+
+// toggleSearchScope is bound to Ctrl-T, switching an in-progress Ctrl-R
+// search between matching every entry and matching only entries run in the
+// current working directory.
+func (s *Session) toggleSearchScope(buf *prompt.Buffer) {
+	cwd, _ := s.fs.Getwd()
+	s.history.toggleScope(buf, cwd)
+}
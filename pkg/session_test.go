@@ -1,9 +1,16 @@
 package wrash
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	prompt "github.com/joshmeranda/go-prompt"
+	"github.com/joshmeranda/wrash/pkg/hub"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGetNextBoundary(t *testing.T) {
@@ -29,3 +36,293 @@ func TestGetNextBoundary(t *testing.T) {
 		})
 	}
 }
+
+func newTestSession(t *testing.T, stdout *bytes.Buffer) *Session {
+	t.Helper()
+
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	s, err := NewSession("echo",
+		OptionInteractive(false),
+		OptionStdout(stdout),
+		OptionStderr(stdout),
+		OptionStatusEnabled(false),
+	)
+	require.NoError(t, err)
+
+	return s
+}
+
+func TestExecutorPipesBuiltinIntoExternalCommand(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.environ["FOO"] = "bar"
+	s.executor("!!env | grep FOO")
+
+	assert.Equal(t, "FOO='bar'\n", out.String())
+}
+
+// TestExecutorPipingIntoBuiltinDoesNotDeadlock guards against a builtin
+// stage never reading app.Reader: the previous stage's io.Pipe Write must
+// still be drained, or the whole pipeline hangs forever instead of just
+// discarding the builtin's unused stdin.
+func TestExecutorPipingIntoBuiltinDoesNotDeadlock(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+	s.environ["FOO"] = "bar"
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.executor("hello | !!env")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("executor deadlocked piping into a builtin")
+	}
+
+	assert.Equal(t, "FOO='bar'\n", out.String())
+}
+
+func TestExecutorBuiltinToBuiltinDoesNotDeadlock(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+	s.environ["FOO"] = "bar"
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.executor("!!env | !!env")
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("executor deadlocked piping a builtin into a builtin")
+	}
+
+	assert.Equal(t, "FOO='bar'\n", out.String())
+}
+
+func TestExecutorOutputRedirection(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	target := filepath.Join(t.TempDir(), "out.txt")
+	s.executor("hi > " + target)
+
+	data, err := os.ReadFile(target)
+	require.NoError(t, err)
+	assert.Equal(t, "hi\n", string(data))
+	assert.Empty(t, out.String())
+}
+
+func TestExecutorExternalPipeline(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("hello world | tr a-z A-Z")
+
+	assert.Equal(t, "HELLO WORLD\n", out.String())
+}
+
+func TestBuiltinReloadPicksUpConfigWrittenAfterStartup(t *testing.T) {
+	var out bytes.Buffer
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+
+	s, err := NewSession("echo",
+		OptionInteractive(false),
+		OptionStdout(&out),
+		OptionStderr(&out),
+		OptionStatusEnabled(false),
+		OptionConfigPath(configPath),
+		OptionWatchCompletions(false),
+	)
+	require.NoError(t, err)
+
+	_, found := s.completers.Alias("echo", "co")
+	assert.False(t, found, "alias should not exist before the config is written")
+
+	require.NoError(t, os.WriteFile(configPath, []byte("aliases:\n  echo:\n    co: checkout\n"), 0666))
+
+	s.executor("!!reload")
+
+	expansion, found := s.completers.Alias("echo", "co")
+	assert.True(t, found, "alias should be loaded after !!reload")
+	assert.Equal(t, "checkout", expansion)
+}
+
+// TestBuiltinReloadPicksUpHubCompletionWrittenAfterStartup guards the other
+// half of the hub's flagship feature: "!!hub install" (simulated here by
+// writing the completion file directly) after a session has already started
+// must be picked up by "!!reload" without restarting the session.
+func TestBuiltinReloadPicksUpHubCompletionWrittenAfterStartup(t *testing.T) {
+	var out bytes.Buffer
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	h := hub.New("", t.TempDir())
+
+	s, err := NewSession("echo",
+		OptionInteractive(false),
+		OptionStdout(&out),
+		OptionStderr(&out),
+		OptionStatusEnabled(false),
+		OptionHub(h),
+		OptionWatchCompletions(false),
+	)
+	require.NoError(t, err)
+
+	assert.Empty(t, s.completers.Completer("echo")(prompt.Document{}), "no suggestions should be registered before the hub completion is installed")
+
+	require.NoError(t, os.MkdirAll(h.CompletionDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(h.CompletionDir, "echo.yaml"), []byte(`
+description: echo
+subcommands:
+  hub-subcommand:
+    description: installed by the hub
+`), 0o644))
+
+	s.executor("!!reload")
+
+	suggestions := s.completers.Completer("echo")(prompt.Document{})
+	assert.Equal(t, []prompt.Suggest{{Text: "hub-subcommand", Description: "installed by the hub"}}, suggestions)
+}
+
+// TestNewSessionLoadsHubInstalledCompletion guards the hub's flagship
+// feature end to end: a completion file dropped into CompletionDir by
+// "!!hub install <name>" must be picked up automatically the next time a
+// session for that base starts, with no config.yaml entry required.
+func TestNewSessionLoadsHubInstalledCompletion(t *testing.T) {
+	var out bytes.Buffer
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	h := hub.New("", t.TempDir())
+	require.NoError(t, os.MkdirAll(h.CompletionDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(h.CompletionDir, "echo.yaml"), []byte(`
+description: echo
+subcommands:
+  hub-subcommand:
+    description: installed by the hub
+`), 0o644))
+
+	s, err := NewSession("echo",
+		OptionInteractive(false),
+		OptionStdout(&out),
+		OptionStderr(&out),
+		OptionStatusEnabled(false),
+		OptionHub(h),
+	)
+	require.NoError(t, err)
+
+	suggestions := s.completers.Completer("echo")(prompt.Document{})
+	assert.Equal(t, []prompt.Suggest{{Text: "hub-subcommand", Description: "installed by the hub"}}, suggestions)
+}
+
+func TestExecutorStatementSequence(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo a; echo b")
+
+	assert.Equal(t, "echo a\necho b\n", out.String())
+}
+
+// fail is a single-stage pipeline that exits non-zero without writing
+// anything to the terminal: the base is only prepended to a pipeline's
+// first stage, so piping into the real "false" binary exercises a failing
+// exit code untouched by that rule.
+const fail = "echo x | false"
+
+func TestExecutorStatementAndSkipsOnFailure(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor(fail + " && echo unreachable")
+
+	assert.Equal(t, "could not run command: exit status 1\n", out.String())
+}
+
+func TestExecutorStatementAndRunsOnSuccess(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo x | true && echo reached")
+
+	assert.Equal(t, "echo reached\n", out.String())
+}
+
+func TestExecutorStatementOrRunsOnFailure(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor(fail + " || echo fallback")
+
+	assert.Equal(t, "echo fallback\n", out.String())
+}
+
+func TestExecutorStatementOrSkipsOnSuccess(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo x | true || echo unreachable")
+
+	assert.Empty(t, out.String())
+}
+
+// Command substitution runs through the same executor path as a top-level
+// command, so its inner command also gets the wrapped Base prepended to its
+// first stage - these tests' expected output accounts for that.
+
+func TestExecutorCommandSubstitution(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo $(echo hi)")
+
+	assert.Equal(t, "echo echo hi\n", out.String())
+}
+
+func TestExecutorCommandSubstitutionBacktick(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo `echo hi`")
+
+	assert.Equal(t, "echo echo hi\n", out.String())
+}
+
+func TestExecutorCommandSubstitutionWordSplitsWhenUnquoted(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo $(printf 'a  b')")
+
+	assert.Equal(t, "echo printf a b\n", out.String())
+}
+
+func TestExecutorCommandSubstitutionQuotedIsSingleToken(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor(`echo "$(printf 'a  b')"`)
+
+	assert.Equal(t, "echo printf a  b\n", out.String())
+}
+
+func TestExecutorCommandSubstitutionErrorSurfaces(t *testing.T) {
+	var out bytes.Buffer
+	s := newTestSession(t, &out)
+
+	s.executor("echo $(!!nonexistent)")
+
+	assert.Equal(t, "could not run command: could not expand args: expansion failed: could not run command substitution \"!!nonexistent\": unknown command: !!nonexistent\n", out.String())
+}
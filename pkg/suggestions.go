@@ -3,7 +3,6 @@ package wrash
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -26,6 +25,10 @@ func LoadSuggestions(p string) (Suggestor, error) {
 		return nil, fmt.Errorf("failed to unmarshal yaml: %w", err)
 	}
 
+	if suggestions.Lsp != nil {
+		return NewLSPSuggestion(suggestions.Lsp, suggestions)
+	}
+
 	return suggestions, nil
 }
 
@@ -50,20 +53,20 @@ type Arg struct {
 	Kind    ArgKind  `yaml:"kind"`
 	Choices []string `yaml:"choices"`
 	Cmd     []string `yaml:"cmd"`
+
+	// Cache, when set, routes Cmd's output through the package-level
+	// completionCache instead of shelling out on every call. See
+	// suggestCached.
+	Cache *CacheConfig `yaml:"cache"`
 }
 
 func (o *Arg) Suggest(arg string) []prompt.Suggest {
 	if len(o.Cmd) > 0 {
-		out, err := exec.Command(o.Cmd[0], o.Cmd[1:]...).Output()
-		if err != nil {
-			return []prompt.Suggest{}
+		if o.Cache != nil {
+			return o.suggestCached(arg)
 		}
 
-		return lo.FilterMap(strings.Split(string(out), "\n"), func(text string, _ int) (prompt.Suggest, bool) {
-			return prompt.Suggest{
-				Text: text,
-			}, strings.HasPrefix(text, arg)
-		})
+		return o.runCmd(arg)
 	}
 
 	if len(o.Choices) > 0 {
@@ -114,6 +117,10 @@ type CommandSuggestion struct {
 	// Flags is only used to determine if a flag expects a value, or when the arg to be completed starts with a dash.
 	Flags map[string]FlagSuggestion `yaml:"flags"`
 	Args  Arg                       `yaml:"args"`
+
+	// Lsp, when set, sources completions for this command from an LSP server
+	// instead of the static Args/SubCommands/Flags below. See NewLSPSuggestion.
+	Lsp *LspConfig `yaml:"lsp"`
 }
 
 func (s *CommandSuggestion) Suggest(args []string, completeLast bool) []prompt.Suggest {
@@ -0,0 +1,55 @@
+package termstatus
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ElapsedProducer is the default StatusProducer: a single status line
+// showing how long the child has been running and the last non-empty line
+// of its output.
+type ElapsedProducer struct {
+	start time.Time
+
+	mu   sync.Mutex
+	last string
+}
+
+// NewElapsedProducer returns an ElapsedProducer whose elapsed time is
+// measured from the moment it is created.
+func NewElapsedProducer() *ElapsedProducer {
+	return &ElapsedProducer{start: time.Now()}
+}
+
+// Reset restarts the elapsed timer from now and clears the last-seen output
+// line, as when a new command is about to start.
+func (p *ElapsedProducer) Reset() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.start = time.Now()
+	p.last = ""
+}
+
+func (p *ElapsedProducer) Feed(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.last = line
+}
+
+func (p *ElapsedProducer) Lines() []string {
+	p.mu.Lock()
+	last := p.last
+	start := p.start
+	p.mu.Unlock()
+
+	elapsed := time.Since(start).Round(time.Second)
+
+	return []string{fmt.Sprintf("▐ elapsed=%s ▌ %s", elapsed, last)}
+}
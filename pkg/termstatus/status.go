@@ -0,0 +1,129 @@
+// Package termstatus renders a persistent multi-line status block at the
+// bottom of the terminal while other output scrolls above it, the same way
+// restic's internal/ui/termstatus package does.
+package termstatus
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// StatusProducer turns the lines of a child process's combined output into
+// the status lines rendered below it (e.g. a spinner, an elapsed timer, the
+// last non-empty line).
+type StatusProducer interface {
+	// Reset is called at the start of each command a Session runs with a
+	// live status block, so a producer measuring state relative to when the
+	// command started (e.g. elapsed time) doesn't carry state over from a
+	// previous command.
+	Reset()
+
+	// Feed is called with each line of the child's combined stdout/stderr as
+	// it arrives.
+	Feed(line string)
+
+	// Lines returns the status lines that should currently be rendered.
+	Lines() []string
+}
+
+// Status owns all writes to a terminal, printing scrolling output above a
+// persistent status block that it keeps redrawn at the bottom. All of its
+// methods are safe to call concurrently; the actual writes happen on the
+// goroutine running Run.
+type Status struct {
+	w           io.Writer
+	interactive bool
+
+	setLinesCh chan []string
+	printCh    chan string
+	done       chan struct{}
+}
+
+// New returns a Status that writes to w. When interactive is false (no TTY,
+// or the session is non-interactive) the status block is never drawn and
+// Print/Error degrade to plain passthrough writes.
+func New(w io.Writer, interactive bool) *Status {
+	return &Status{
+		w:           w,
+		interactive: interactive,
+
+		setLinesCh: make(chan []string),
+		printCh:    make(chan string),
+		done:       make(chan struct{}),
+	}
+}
+
+// SetLines replaces the contents of the status block.
+func (s *Status) SetLines(lines []string) {
+	select {
+	case s.setLinesCh <- lines:
+	case <-s.done:
+	}
+}
+
+// Print writes a line of scrolling output above the status block.
+func (s *Status) Print(line string) {
+	select {
+	case s.printCh <- line:
+	case <-s.done:
+	}
+}
+
+// Error writes a line of scrolling output above the status block. It is
+// distinguished from Print only by intent; both scroll above the block.
+func (s *Status) Error(line string) {
+	s.Print(line)
+}
+
+// Run owns all writes to the terminal until ctx is cancelled, at which point
+// it clears the status block and returns. Run must be called from its own
+// goroutine.
+func (s *Status) Run(ctx context.Context) {
+	defer close(s.done)
+
+	var current []string
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.clear(len(current))
+			return
+		case lines := <-s.setLinesCh:
+			s.clear(len(current))
+			current = lines
+			s.draw(current)
+		case line := <-s.printCh:
+			s.clear(len(current))
+			fmt.Fprintln(s.w, line)
+			s.draw(current)
+		}
+	}
+}
+
+// clear erases the n-line status block and returns the cursor to the
+// position it held before the block was drawn.
+func (s *Status) clear(n int) {
+	if !s.interactive || n == 0 {
+		return
+	}
+
+	fmt.Fprintf(s.w, "\x1b[%dA", n)
+	for i := 0; i < n; i++ {
+		fmt.Fprint(s.w, "\r\x1b[2K")
+		fmt.Fprint(s.w, "\x1b[1B")
+	}
+}
+
+// draw renders lines as the status block, leaving the cursor just past it.
+func (s *Status) draw(lines []string) {
+	if !s.interactive {
+		return
+	}
+
+	for _, line := range lines {
+		fmt.Fprint(s.w, "\r\x1b[2K")
+		fmt.Fprint(s.w, line)
+		fmt.Fprint(s.w, "\r\n")
+	}
+}
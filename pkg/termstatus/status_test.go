@@ -0,0 +1,76 @@
+package termstatus
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusNonInteractivePassthrough(t *testing.T) {
+	out := &strings.Builder{}
+	status := New(out, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go status.Run(ctx)
+
+	status.SetLines([]string{"should not be drawn"})
+	status.Print("hello")
+	status.Error("world")
+
+	cancel()
+	// give the Run goroutine a moment to observe ctx.Done and return
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, "hello\nworld\n", out.String())
+}
+
+func TestStatusInteractiveDrawsBlockAndClearsOnPrint(t *testing.T) {
+	out := &strings.Builder{}
+	status := New(out, true)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go status.Run(ctx)
+	defer cancel()
+
+	status.SetLines([]string{"line one"})
+	status.Print("scrolled output")
+
+	time.Sleep(10 * time.Millisecond)
+
+	output := out.String()
+	assert.Contains(t, output, "line one")
+	assert.Contains(t, output, "scrolled output")
+	assert.Contains(t, output, "\x1b[2K")
+	assert.Contains(t, output, "\x1b[1A")
+}
+
+func TestElapsedProducer(t *testing.T) {
+	p := NewElapsedProducer()
+	p.Feed("")
+	p.Feed("some output")
+
+	lines := p.Lines()
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "some output")
+	assert.Contains(t, lines[0], "elapsed=")
+}
+
+// TestElapsedProducerReset guards a per-command status line: without Reset,
+// a producer reused across commands (as Session's is) would keep reporting
+// elapsed time since the session started rather than since the current
+// command started.
+func TestElapsedProducerReset(t *testing.T) {
+	p := NewElapsedProducer()
+	p.Feed("leftover output from the previous command")
+	time.Sleep(1100 * time.Millisecond)
+
+	p.Reset()
+
+	lines := p.Lines()
+	assert.Len(t, lines, 1)
+	assert.Contains(t, lines[0], "elapsed=0s")
+	assert.NotContains(t, lines[0], "leftover output from the previous command")
+}
@@ -7,9 +7,7 @@ import (
 	"regexp"
 	"strings"
 
-	wrash "github.com/joshmeranda/wrash/pkg"
 	"github.com/samber/lo"
-	"gopkg.in/yaml.v3"
 )
 
 var identiferPattern = regexp.MustCompile("^[a-zA-Z0-9_]+$")
@@ -45,22 +43,3 @@ func loadEnviron(extra map[string]string) map[string]string {
 
 	return env
 }
-
-func loadHistoryEntries(path string) ([]*wrash.Entry, error) {
-	var entries []*wrash.Entry
-
-	data, err := os.ReadFile(path)
-	if os.IsNotExist(err) {
-		return entries, nil
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("could not read history file: %w", err)
-	}
-
-	if err := yaml.Unmarshal(data, &entries); err != nil {
-		return nil, fmt.Errorf("could not unmarshal history entries: %w", err)
-	}
-
-	return entries, nil
-}